@@ -0,0 +1,15 @@
+// Copyright 2023-2025 Roel Harbers.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package byteslicechan
+
+import (
+	"testing"
+
+	"github.com/ComaVN/multee/multeetest"
+)
+
+func TestConformance(t *testing.T) {
+	multeetest.Run(t, NewMulteeReader)
+}
@@ -0,0 +1,133 @@
+// Copyright 2023-2025 Roel Harbers.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package multee_test
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/ComaVN/multee"
+	"github.com/ComaVN/multee/alt/byteslicechan"
+)
+
+// The implementations to compare, by name.
+var benchmarkImpls = []struct {
+	name    string
+	factory func(io.Reader) multee.MulteeReader
+}{
+	{"Ring", func(inputReader io.Reader) multee.MulteeReader {
+		return multee.NewMulteeReader(inputReader)
+	}},
+	{"Ring_with_lookahead", func(inputReader io.Reader) multee.MulteeReader {
+		return multee.NewMulteeReader(inputReader, multee.WithLookahead(1024*1024))
+	}},
+	{"Barrier", func(inputReader io.Reader) multee.MulteeReader {
+		return multee.NewMulteeReader(inputReader, multee.WithStrategy(multee.StrategyBarrier))
+	}},
+	{"Channel", func(inputReader io.Reader) multee.MulteeReader {
+		return multee.NewMulteeReader(inputReader, multee.WithStrategy(multee.StrategyChannel))
+	}},
+	{"Reader_at", func(inputReader io.Reader) multee.MulteeReader {
+		ra := inputReader.(*io.SectionReader)
+		return multee.NewMulteeReaderAt(ra, ra.Size())
+	}},
+	{"Alt_byteslicechan", byteslicechan.NewMulteeReader},
+}
+
+var (
+	benchmarkReaderCnts = []int{1, 2, 8, 64}
+	benchmarkReadSizes  = []int{1, 512, 32 * 1024, 4 * 1024 * 1024}
+)
+
+// Reads from each reader into a buffer of readSize bytes, until EOF.
+// The reported MB/s is the number of input bytes per second, regardless of the number of readers.
+func BenchmarkMultee(b *testing.B) {
+	for _, impl := range benchmarkImpls {
+		b.Run(impl.name, func(b *testing.B) {
+			for _, readerCnt := range benchmarkReaderCnts {
+				b.Run(fmt.Sprintf("Readers_%d", readerCnt), func(b *testing.B) {
+					for _, readSize := range benchmarkReadSizes {
+						b.Run(fmt.Sprintf("Read_size_%d", readSize), func(b *testing.B) {
+							benchmarkMultee(b, impl.factory, readerCnt, readSize, false)
+						})
+					}
+				})
+			}
+		})
+	}
+}
+
+// Like BenchmarkMultee, but the first reader also hashes everything it reads, which makes it slower than the others.
+func BenchmarkMultee_skewed(b *testing.B) {
+	const readSize = 32 * 1024
+	for _, impl := range benchmarkImpls {
+		b.Run(impl.name, func(b *testing.B) {
+			for _, readerCnt := range benchmarkReaderCnts[1:] {
+				b.Run(fmt.Sprintf("Readers_%d", readerCnt), func(b *testing.B) {
+					benchmarkMultee(b, impl.factory, readerCnt, readSize, true)
+				})
+			}
+		})
+	}
+}
+
+func benchmarkMultee(b *testing.B, factory func(io.Reader) multee.MulteeReader, readerCnt int, readSize int, skewed bool) {
+	// Reading 1 byte at a time is slow, so the input is smaller for small read sizes, to keep each iteration short.
+	inputSize := int64(min(max(readSize*1024, 64*1024), 8*1024*1024))
+	b.SetBytes(inputSize)
+	b.ReportAllocs()
+	bufs := make([][]byte, readerCnt)
+	for idx := range bufs {
+		bufs[idx] = make([]byte, readSize)
+	}
+	h := sha256.New()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		mr := factory(io.NewSectionReader(zeroReader{}, 0, inputSize))
+		readers := make([]multee.Reader, readerCnt)
+		for idx := range readers {
+			readers[idx] = mr.NewReader()
+		}
+		var wg sync.WaitGroup
+		wg.Add(readerCnt)
+		for idx, r := range readers {
+			var w hash.Hash
+			if skewed && idx == 0 {
+				h.Reset()
+				w = h
+			}
+			go func(r multee.Reader, buf []byte, w hash.Hash) {
+				defer wg.Done()
+				defer r.Close()
+				for {
+					n, err := r.Read(buf)
+					if w != nil {
+						w.Write(buf[:n])
+					}
+					if err == io.EOF {
+						return
+					}
+					if err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			}(r, bufs[idx], w)
+		}
+		wg.Wait()
+	}
+}
+
+// This is an infinite source of zero bytes, which doesn't allocate, so only the allocations of the implementation are reported.
+type zeroReader struct{}
+
+func (zeroReader) ReadAt(p []byte, off int64) (int, error) {
+	clear(p)
+	return len(p), nil
+}
@@ -0,0 +1,325 @@
+// Copyright 2023-2025 Roel Harbers.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package multee
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// This is the MulteeReader for StrategyChannel.
+// A pump goroutine reads from the input reader, and sends each chunk to the channel of every reader.
+type chanMulteeReader struct {
+	inputReader io.Reader
+	cfg         config
+	abortC      chan struct{}            // This is closed when abortErr is set.
+	mu          sync.Mutex               // This guards all fields below.
+	err         error                    // This is set when the input reader has returned an error, after which the pump stops.
+	abortErr    error                    // This is set when all readers have been aborted, after which the pump stops.
+	pumping     bool                     // This is set while the pump goroutine is running.
+	readers     map[*chanReader]struct{} // Unordered set of readers that haven't been detached.
+	offset      int64                    // Number of bytes read from the input reader.
+	chunks      uint64
+}
+
+func newChanMulteeReader(inputReader io.Reader, cfg config) *chanMulteeReader {
+	return &chanMulteeReader{
+		inputReader: inputReader,
+		cfg:         cfg,
+		abortC:      make(chan struct{}),
+		readers:     make(map[*chanReader]struct{}),
+	}
+}
+
+// Returns an io.ReadCloser. The caller must either keep reading until EOF or call Close(),
+// or the MulteeReader will block.
+// The returned reader is *not* concurrency-safe.
+// It is safe to call NewReader while other readers are reading, the new reader will start at the next chunk
+// read from the input reader.
+func (mr *chanMulteeReader) NewReader() Reader {
+	return mr.newReader()
+}
+
+// Like NewReader, pos is ignored, since readers always start at the next chunk read from the input reader.
+func (mr *chanMulteeReader) NewReaderJoining(pos JoinPosition) Reader {
+	return mr.newReader()
+}
+
+// Like NewReader, but when ctx is done, the reader is detached from the multeeReader, as if it was closed,
+// and all current and future reads from it return an error matching both ctx.Err() and ErrCanceled.
+// The reader must still be closed.
+func (mr *chanMulteeReader) NewReaderContext(ctx context.Context) Reader {
+	r := mr.newReader()
+	r.stopCtx = context.AfterFunc(ctx, func() {
+		r.detach(canceledError(ctx.Err()))
+	})
+	return r
+}
+
+// Not supported by StrategyChannel, errors.ErrUnsupported is always returned.
+func (mr *chanMulteeReader) NewReaderFrom(offset int64) (Reader, error) {
+	return nil, errors.ErrUnsupported
+}
+
+// Returns the number of bytes read from the input reader so far.
+func (mr *chanMulteeReader) SourceOffset() int64 {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	return mr.offset
+}
+
+// Returns the current statistics of the multeeReader.
+func (mr *chanMulteeReader) Stats() Stats {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	return Stats{
+		SourceBytes: mr.offset,
+		Chunks:      mr.chunks,
+		Readers:     len(mr.readers),
+	}
+}
+
+func (mr *chanMulteeReader) newReader() *chanReader {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	r := &chanReader{
+		multeeReader: mr,
+		c:            make(chan []byte, mr.cfg.ringSize()),
+		detachedC:    make(chan struct{}),
+	}
+	r.offset.Store(mr.offset)
+	r.guard.checked = mr.cfg.checked
+	mr.cfg.observers.readerJoin()
+	if mr.err != nil {
+		// The input reader is done, so this reader only gets its error.
+		close(r.c)
+		return r
+	}
+	mr.readers[r] = struct{}{}
+	return r
+}
+
+// Makes all current and future reads from all readers return err, and stops the pump.
+func (mr *chanMulteeReader) fail(err error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	if mr.abortErr != nil {
+		return
+	}
+	mr.abortErr = err
+	mr.cfg.observers.error(err)
+	close(mr.abortC)
+}
+
+// Starts the pump goroutine, unless it's already running, or the input reader is done.
+func (mr *chanMulteeReader) startPump() {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	if mr.pumping || mr.err != nil || mr.abortErr != nil {
+		return
+	}
+	mr.pumping = true
+	go mr.pump()
+}
+
+// Reads from the input reader and multiplexes to all reader channels, while there is input, no errors, and unclosed readers.
+func (mr *chanMulteeReader) pump() {
+	for {
+		mr.mu.Lock()
+		if len(mr.readers) == 0 || mr.abortErr != nil {
+			// All readers have been closed, the next Read of a new reader starts a new pump.
+			mr.pumping = false
+			mr.mu.Unlock()
+			return
+		}
+		mr.mu.Unlock()
+		buf := make([]byte, mr.cfg.chunkSize)
+		n, err := mr.inputReader.Read(buf)
+		mr.mu.Lock()
+		mr.offset += int64(n)
+		mr.chunks++
+		mr.cfg.observers.chunkLoad(n, err)
+		// Readers that are added from now on start at the next chunk.
+		readers := make([]*chanReader, 0, len(mr.readers))
+		for r := range mr.readers {
+			readers = append(readers, r)
+		}
+		mr.mu.Unlock()
+		if n > 0 {
+			for _, r := range readers {
+				select { // This blocks while the reader's channel is full and the reader is not detached.
+				case r.c <- buf[:n]:
+				case <-r.detachedC:
+				case <-mr.abortC:
+				}
+			}
+		}
+		if err != nil {
+			mr.mu.Lock()
+			mr.err = sourceError(err, mr.offset)
+			if mr.err != io.EOF {
+				mr.cfg.observers.error(mr.err)
+			}
+			mr.pumping = false
+			for r := range mr.readers {
+				close(r.c)
+			}
+			mr.mu.Unlock()
+			return
+		}
+	}
+}
+
+// This is the io.ReadCloser returned by chanMulteeReader.NewReader.
+type chanReader struct {
+	multeeReader *chanMulteeReader
+	c            chan []byte
+	buf          []byte        // The bytes of the last chunk from c that didn't fit into p.
+	err          error         // This is set when the reader has been detached, guarded by the multeeReader's mu.
+	detachedC    chan struct{} // This is closed when err is set.
+	stopCtx      func() bool   // This stops detaching the reader when its context is done, if it has one.
+	closed       bool
+	guard        useGuard
+	// These are atomic, so Offset, Lag and Stats can be called concurrently with Read.
+	offset  atomic.Int64 // Absolute offset in the input of the next byte this reader reads.
+	bytes   atomic.Int64
+	reads   atomic.Int64
+	blocked atomic.Int64
+}
+
+func (r *chanReader) Read(p []byte) (n int, err error) {
+	mr := r.multeeReader
+	if err := r.guard.acquire(); err != nil {
+		return 0, err
+	}
+	defer r.guard.release()
+	if r.closed {
+		return 0, ErrClosed
+	}
+	r.reads.Add(1)
+	if err := r.detachedErr(); err != nil {
+		return 0, err
+	}
+	defer func() {
+		if n > 0 {
+			r.advance(n)
+		}
+	}()
+	if len(p) == 0 {
+		return 0, nil
+	}
+	mr.startPump()
+	// First use the bytes buffered by the previous Read, if any.
+	// Like any io.Reader, this returns the bytes that are available, rather than waiting for p to be filled.
+	if len(r.buf) > 0 {
+		n = copy(p, r.buf)
+		r.buf = r.buf[n:]
+		return n, nil
+	}
+	bs, err := r.next()
+	if err != nil {
+		return 0, err
+	}
+	n = copy(p, bs)
+	// Not all bytes from the chunk may fit into p, buffer the rest for the next Read.
+	r.buf = bs[n:]
+	return n, nil
+}
+
+// Moves the reader n bytes forward in the input.
+func (r *chanReader) advance(n int) {
+	r.offset.Add(int64(n))
+	r.bytes.Add(int64(n))
+}
+
+// Waits for the next chunk from the pump, and returns it.
+// Must only be called after startPump.
+func (r *chanReader) next() ([]byte, error) {
+	mr := r.multeeReader
+	var bs []byte
+	var ok bool
+	start := time.Now()
+	select {
+	case bs, ok = <-r.c:
+	case <-r.detachedC:
+		return nil, r.detachedErr()
+	case <-mr.abortC:
+		return nil, r.detachedErr()
+	}
+	r.blocked.Add(int64(time.Since(start)))
+	if !ok {
+		// The channel is only closed after err is set, so this doesn't need locking.
+		return nil, mr.err
+	}
+	return bs, nil
+}
+
+// Returns the error of a detached reader, or the error all readers were aborted with, if any.
+func (r *chanReader) detachedErr() error {
+	mr := r.multeeReader
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	return mr.abortErr
+}
+
+// Returns the absolute offset in the input of the next byte this reader reads.
+// Unlike Read, this is concurrency-safe, and it can be called after Close.
+func (r *chanReader) Offset() int64 {
+	return r.offset.Load()
+}
+
+// Returns the number of bytes that have been read from the input reader, but not by this reader yet.
+// Like Offset, this is concurrency-safe.
+func (r *chanReader) Lag() int64 {
+	return r.multeeReader.SourceOffset() - r.offset.Load()
+}
+
+// Returns the current statistics of the reader.
+func (r *chanReader) Stats() ReaderStats {
+	return ReaderStats{
+		Bytes:   r.bytes.Load(),
+		Reads:   r.reads.Load(),
+		Blocked: time.Duration(r.blocked.Load()),
+	}
+}
+
+func (r *chanReader) Close() error {
+	if err := r.guard.acquire(); err != nil {
+		return err
+	}
+	defer r.guard.release()
+	if r.closed {
+		return ErrClosed
+	}
+	if r.stopCtx != nil {
+		r.stopCtx()
+	}
+	r.closed = true
+	r.detach(ErrClosed)
+	return nil
+}
+
+// Removes the reader from the multeeReader, so the pump no longer sends to it.
+// All reads from the reader will return err from now on.
+func (r *chanReader) detach(err error) {
+	mr := r.multeeReader
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	if r.err != nil {
+		// Already detached.
+		return
+	}
+	r.err = err
+	delete(mr.readers, r)
+	mr.cfg.observers.readerClose(err)
+	close(r.detachedC)
+}
@@ -0,0 +1,83 @@
+// Copyright 2023-2025 Roel Harbers.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package multee
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_chanMulteeReader_NewReader_while_reading(t *testing.T) {
+	mr := NewMulteeReader(iotest.OneByteReader(strings.NewReader("foobar")), WithStrategy(StrategyChannel))
+	r1 := mr.NewReader()
+	defer r1.Close()
+	p := make([]byte, 3)
+	_, err := io.ReadFull(r1, p)
+	assert.NoError(t, err)
+	r2 := mr.NewReader()
+	defer r2.Close()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		b, err := io.ReadAll(r2)
+		// r2 starts at the next chunk, which may already be read from the input reader.
+		assert.True(t, strings.HasSuffix("foobar", string(b)))
+		assert.Equal(t, int64(6), r2.Offset())
+		assert.Nil(t, err)
+	}()
+	b, err := io.ReadAll(r1)
+	assert.Equal(t, []byte("bar"), b)
+	assert.Nil(t, err)
+	<-done
+	// Readers added after EOF get EOF right away.
+	r3 := mr.NewReader()
+	defer r3.Close()
+	_, err = r3.Read(p)
+	assert.Equal(t, io.EOF, err)
+}
+
+func Test_chanMulteeReader_pump_stops(t *testing.T) {
+	ir, iw := io.Pipe()
+	defer iw.Close()
+	go func() {
+		for {
+			if _, err := iw.Write([]byte("foo")); err != nil {
+				return
+			}
+		}
+	}()
+	mr := NewMulteeReader(ir, WithStrategy(StrategyChannel)).(*chanMulteeReader)
+	r := mr.NewReader()
+	_, err := io.ReadFull(r, make([]byte, 6))
+	assert.NoError(t, err)
+	assert.NoError(t, r.Close())
+	// The pump notices all readers are closed, after its next read from the input reader.
+	assert.Eventually(t, func() bool {
+		mr.mu.Lock()
+		defer mr.mu.Unlock()
+		return !mr.pumping
+	}, time.Second, time.Millisecond)
+}
+
+func Test_chanMulteeReader_canceled(t *testing.T) {
+	ir, iw := io.Pipe()
+	defer iw.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	mr := NewMulteeReaderContext(ctx, ir, WithStrategy(StrategyChannel))
+	r := mr.NewReader()
+	defer r.Close()
+	go cancel()
+	// The pump is waiting for the input reader, but the reader returns right away.
+	_, err := r.Read(make([]byte, 1))
+	assert.True(t, errors.Is(err, ErrCanceled))
+	assert.True(t, errors.Is(err, context.Canceled))
+}
@@ -0,0 +1,132 @@
+// Copyright 2023-2025 Roel Harbers.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+//go:build go1.23
+
+package multee
+
+import (
+	"errors"
+	"io"
+	"iter"
+	"runtime"
+)
+
+// Chunker is implemented by the readers of NewMulteeReader and NewMulteeWriter, when built with Go 1.23 or later.
+// The readers of NewMulteeReaderAt don't implement it, since they don't buffer the input in chunks.
+type Chunker interface {
+	// Chunks returns an iterator over the remaining input, in chunks as read from the input reader,
+	// that are shared with the other readers, instead of being copied into a buffer.
+	// The chunks are read-only, and only valid until the next iteration.
+	// When there is an error, other than io.EOF, it is yielded with a nil chunk, and the iteration ends.
+	// Like after Read, the reader must still be closed, unless the iteration ended because the input ended.
+	Chunks() iter.Seq2[[]byte, error]
+}
+
+var (
+	_ Chunker = (*reader)(nil)
+	_ Chunker = (*trackedReader)(nil)
+	_ Chunker = (*chanReader)(nil)
+)
+
+// Returns an iterator over the remaining bytes of r, see Chunker.
+// When r doesn't implement Chunker, the bytes are read into a buffer, which is only valid until the next iteration.
+func Chunks(r io.Reader) iter.Seq2[[]byte, error] {
+	if c, ok := r.(Chunker); ok {
+		return c.Chunks()
+	}
+	return func(yield func([]byte, error) bool) {
+		buf := make([]byte, bufferSize)
+		for {
+			n, err := r.Read(buf)
+			if n > 0 && !yield(buf[:n], nil) {
+				return
+			}
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+		}
+	}
+}
+
+// This is returned by the function passed to each, when the iteration is stopped early.
+var errStopped = errors.New("iteration stopped")
+
+func (r *reader) Chunks() iter.Seq2[[]byte, error] {
+	return func(yield func([]byte, error) bool) {
+		if err := r.guard.acquire(); err != nil {
+			yield(nil, err)
+			return
+		}
+		defer r.guard.release()
+		if r.closed {
+			yield(nil, ErrClosed)
+			return
+		}
+		// Once yield has returned false, it must not be called again, whatever each returns.
+		stopped := false
+		_, err := r.multeeReader.each(r, func(buf []byte) (int, error) {
+			if !yield(buf, nil) {
+				stopped = true
+				return len(buf), errStopped
+			}
+			return len(buf), nil
+		})
+		if err != nil && !stopped {
+			yield(nil, err)
+		}
+	}
+}
+
+// This makes sure tr isn't garbage collected, and the reader closed, while it's being iterated.
+func (tr *trackedReader) Chunks() iter.Seq2[[]byte, error] {
+	return func(yield func([]byte, error) bool) {
+		defer runtime.KeepAlive(tr)
+		tr.reader.Chunks()(yield)
+	}
+}
+
+func (r *chanReader) Chunks() iter.Seq2[[]byte, error] {
+	return func(yield func([]byte, error) bool) {
+		if err := r.guard.acquire(); err != nil {
+			yield(nil, err)
+			return
+		}
+		defer r.guard.release()
+		if r.closed {
+			yield(nil, ErrClosed)
+			return
+		}
+		r.reads.Add(1)
+		if err := r.detachedErr(); err != nil {
+			yield(nil, err)
+			return
+		}
+		r.multeeReader.startPump()
+		// First use the bytes buffered by the previous Read, if any.
+		bs := r.buf
+		r.buf = nil
+		for {
+			if len(bs) > 0 {
+				r.advance(len(bs))
+				if !yield(bs, nil) {
+					return
+				}
+			}
+			var err error
+			bs, err = r.next()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+		}
+	}
+}
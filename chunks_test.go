@@ -0,0 +1,159 @@
+// Copyright 2023-2025 Roel Harbers.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+//go:build go1.23
+
+package multee
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChunks(t *testing.T) {
+	in := strings.Repeat("foobar", 1000)
+	factories := []struct {
+		name    string
+		factory func(io.Reader) MulteeReader
+	}{
+		{"Ring", func(inputReader io.Reader) MulteeReader {
+			return NewMulteeReader(inputReader, WithChunkSize(100), WithLookahead(300))
+		}},
+		{"Spill_to_disk", func(inputReader io.Reader) MulteeReader {
+			return NewMulteeReader(inputReader, WithChunkSize(100), WithSpillToDisk(t.TempDir(), 0))
+		}},
+		{"Debug", func(inputReader io.Reader) MulteeReader {
+			return NewMulteeReader(inputReader, WithChunkSize(100), WithDebug(Debug{}))
+		}},
+		{"Channel", func(inputReader io.Reader) MulteeReader {
+			return NewMulteeReader(inputReader, WithStrategy(StrategyChannel), WithChunkSize(100))
+		}},
+	}
+	for _, f := range factories {
+		t.Run(f.name, func(t *testing.T) {
+			t.Run("All", func(t *testing.T) {
+				mr := f.factory(strings.NewReader(in))
+				readers := []Reader{mr.NewReader(), mr.NewReader()}
+				got := make([]string, len(readers))
+				done := make(chan struct{})
+				for idx, r := range readers {
+					go func() {
+						defer func() { done <- struct{}{} }()
+						defer r.Close()
+						var b bytes.Buffer
+						for chunk, err := range Chunks(r) {
+							assert.NoError(t, err)
+							b.Write(chunk)
+						}
+						got[idx] = b.String()
+					}()
+				}
+				for range readers {
+					<-done
+				}
+				for idx := range readers {
+					assert.Equal(t, in, got[idx], "reader %d", idx)
+				}
+			})
+			t.Run("Break", func(t *testing.T) {
+				mr := f.factory(strings.NewReader(in))
+				r := mr.NewReader()
+				defer r.Close()
+				// Read a few bytes first, so the first chunk is only partially left.
+				p := make([]byte, 3)
+				_, err := io.ReadFull(r, p)
+				assert.NoError(t, err)
+				var first []byte
+				for chunk, err := range Chunks(r) {
+					assert.NoError(t, err)
+					first = bytes.Clone(chunk)
+					break
+				}
+				assert.True(t, strings.HasPrefix(in[3:], string(first)))
+				// Reading continues after the chunk.
+				b, err := io.ReadAll(r)
+				assert.NoError(t, err)
+				assert.Equal(t, in[3+len(first):], string(b))
+			})
+			// Breaking after the reader was detached must not make the iterator yield its error.
+			t.Run("Break_after_close", func(t *testing.T) {
+				mr := f.factory(strings.NewReader(in))
+				r := mr.NewReader()
+				for _, err := range Chunks(r) {
+					assert.NoError(t, err)
+					assert.NoError(t, r.Close())
+					break
+				}
+			})
+			t.Run("Break_after_cancel", func(t *testing.T) {
+				ctx, cancel := context.WithCancel(context.Background())
+				defer cancel()
+				mr := f.factory(strings.NewReader(in))
+				r := mr.NewReaderContext(ctx)
+				defer r.Close()
+				for _, err := range Chunks(r) {
+					assert.NoError(t, err)
+					cancel()
+					assert.Eventually(t, func() bool {
+						return mr.Stats().Readers == 0
+					}, time.Second, time.Millisecond)
+					break
+				}
+			})
+			t.Run("Panic", func(t *testing.T) {
+				mr := f.factory(strings.NewReader(in))
+				r := mr.NewReader()
+				assert.PanicsWithValue(t, "foo", func() {
+					for range Chunks(r) {
+						panic("foo")
+					}
+				})
+				assert.NoError(t, r.Close())
+				assert.Equal(t, 0, mr.Stats().Readers)
+			})
+			t.Run("Error", func(t *testing.T) {
+				mr := f.factory(iotest.TimeoutReader(strings.NewReader(in)))
+				r := mr.NewReader()
+				defer r.Close()
+				var errs []error
+				for _, err := range Chunks(r) {
+					if err != nil {
+						errs = append(errs, err)
+					}
+				}
+				if assert.Len(t, errs, 1) {
+					assert.True(t, errors.Is(errs[0], iotest.ErrTimeout))
+				}
+			})
+			t.Run("Closed", func(t *testing.T) {
+				mr := f.factory(strings.NewReader(in))
+				r := mr.NewReader()
+				assert.NoError(t, r.Close())
+				for _, err := range Chunks(r) {
+					assert.Equal(t, ErrClosed, err)
+				}
+			})
+		})
+	}
+	t.Run("Fallback", func(t *testing.T) {
+		// The readers of NewMulteeReaderAt don't implement Chunker.
+		mr := NewMulteeReaderAt(strings.NewReader(in), int64(len(in)))
+		r := mr.NewReader()
+		defer r.Close()
+		var b bytes.Buffer
+		for chunk, err := range Chunks(r) {
+			assert.NoError(t, err)
+			b.Write(chunk)
+		}
+		assert.Equal(t, in, b.String())
+	})
+}
@@ -0,0 +1,199 @@
+// Copyright 2023-2025 Roel Harbers.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+// Command multee copies its input to several commands, files and stdout at once, like tee,
+// with backpressure: the input is read only as fast as the slowest branch consumes it.
+//
+// Usage:
+//
+//	multee [flags] [command...]
+//
+// Each command is run by sh -c, with the input as its stdin. For example:
+//
+//	multee -o copy.bin 'sha256sum' 'gzip > copy.bin.gz' < input.bin
+//
+// multee waits for all branches, and exits with a non-zero status if any of them failed.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ComaVN/multee"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	os.Exit(run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// Runs multee with the given command-line arguments, and returns its exit status.
+func run(ctx context.Context, args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("multee", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: multee [flags] [command...]\n\n")
+		fmt.Fprintf(flags.Output(), "Copies the input to each command, which is run by sh -c, and to each output file.\n\n")
+		flags.PrintDefaults()
+	}
+	var (
+		input      = flags.String("i", "", "read the input from this `file`, instead of stdin")
+		outputs    stringsFlag
+		toStdout   = flags.Bool("stdout", false, "also copy the input to stdout")
+		failFast   = flags.Bool("fail-fast", false, "stop all branches when any of them fails")
+		chunkSize  = flags.Int("chunk-size", 0, "read the input in chunks of at most this many `bytes` (default 32 KiB)")
+		lookahead  = flags.Int("lookahead", 0, "let fast branches run this many `bytes` ahead of the slowest branch")
+		slowPolicy = flags.String("slow", "block", "`policy` for branches that hold up the others: block, detach or fail")
+		maxStall   = flags.Duration("max-stall", 0, "a branch is too slow when it holds up the others for longer than this `duration`")
+		maxLag     = flags.Int64("max-lag", 0, "a branch is too slow when it falls this many `bytes` behind, while holding up the others")
+	)
+	flags.Var(&outputs, "o", "also copy the input to this `file`, may be repeated")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	policy, ok := map[string]multee.SlowReaderPolicy{
+		"block":  multee.SlowReaderBlock,
+		"detach": multee.SlowReaderDetach,
+		"fail":   multee.SlowReaderFail,
+	}[*slowPolicy]
+	if !ok {
+		fmt.Fprintf(stderr, "multee: invalid slow reader policy %q\n", *slowPolicy)
+		flags.Usage()
+		return 2
+	}
+	if _, ok := stdout.(*os.File); !ok {
+		// The branches write to stdout concurrently, which is only safe for files.
+		stdout = &syncWriter{w: stdout}
+	}
+	if _, ok := stderr.(*os.File); !ok {
+		stderr = &syncWriter{w: stderr}
+	}
+	var fns []multee.ConsumerFunc
+	for _, command := range flags.Args() {
+		fns = append(fns, commandBranch(command, stdout, stderr))
+	}
+	for _, name := range outputs {
+		fns = append(fns, fileBranch(name))
+	}
+	if *toStdout {
+		fns = append(fns, writerBranch(stdout))
+	}
+	if len(fns) == 0 {
+		fmt.Fprintf(stderr, "multee: no commands or outputs given\n")
+		flags.Usage()
+		return 2
+	}
+	src := stdin
+	if *input != "" {
+		f, err := os.Open(*input)
+		if err != nil {
+			fmt.Fprintf(stderr, "multee: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		src = f
+	}
+	runner := multee.Runner{
+		Options: []multee.Option{
+			multee.WithChunkSize(*chunkSize),
+			multee.WithLookahead(*lookahead),
+			multee.WithSlowReaderPolicy(policy, *maxStall, *maxLag),
+		},
+		CancelOnError: *failFast,
+	}
+	if err := runner.Run(ctx, src, fns...); err != nil {
+		fmt.Fprintf(stderr, "multee: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// Returns a branch that runs command using sh -c, with its input as stdin.
+func commandBranch(command string, stdout io.Writer, stderr io.Writer) multee.ConsumerFunc {
+	return func(ctx context.Context, r io.Reader) error {
+		cmd := exec.CommandContext(ctx, "sh", "-c", command)
+		cmd.Stdout = stdout
+		cmd.Stderr = stderr
+		// Don't wait forever for the output of background processes the command started, when it's killed.
+		cmd.WaitDelay = time.Second
+		// The input is copied by this goroutine, instead of one started by exec, so the reader is never used after this returns.
+		stdin, err := cmd.StdinPipe()
+		if err != nil {
+			return err
+		}
+		if err := cmd.Start(); err != nil {
+			return fmt.Errorf("command %q: %w", command, err)
+		}
+		_, copyErr := io.Copy(stdin, r)
+		stdin.Close()
+		err = cmd.Wait()
+		if ctx.Err() != nil {
+			// The command was killed, because another branch failed.
+			return ctx.Err()
+		}
+		if err == nil && copyErr != nil && !errors.Is(copyErr, syscall.EPIPE) {
+			// A command may exit without reading all of its input, but reading the input may not fail.
+			err = copyErr
+		}
+		if err != nil {
+			return fmt.Errorf("command %q: %w", command, err)
+		}
+		return nil
+	}
+}
+
+// Returns a branch that writes its input to the file with the given name, which is created or truncated.
+func fileBranch(name string) multee.ConsumerFunc {
+	return func(ctx context.Context, r io.Reader) error {
+		f, err := os.Create(name)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(f, r)
+		return errors.Join(err, f.Close())
+	}
+}
+
+// Returns a branch that writes its input to w.
+func writerBranch(w io.Writer) multee.ConsumerFunc {
+	return func(ctx context.Context, r io.Reader) error {
+		_, err := io.Copy(w, r)
+		return err
+	}
+}
+
+// This is a flag that may be repeated, collecting all its values.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ", ")
+}
+
+func (f *stringsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// This makes writes to w concurrency-safe.
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (sw *syncWriter) Write(p []byte) (int, error) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	return sw.w.Write(p)
+}
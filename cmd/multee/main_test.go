@@ -0,0 +1,69 @@
+// Copyright 2023-2025 Roel Harbers.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_run(t *testing.T) {
+	in := strings.Repeat("foobar", 100000)
+	t.Run("Branches", func(t *testing.T) {
+		dir := t.TempDir()
+		var stdout, stderr bytes.Buffer
+		status := run(context.Background(), []string{
+			"-o", filepath.Join(dir, "out1"),
+			"-o", filepath.Join(dir, "out2"),
+			"-stdout",
+			"cat > " + filepath.Join(dir, "cat"),
+			"head -c 3 > " + filepath.Join(dir, "head"),
+		}, strings.NewReader(in), &stdout, &stderr)
+		assert.Equal(t, 0, status, stderr.String())
+		assert.Equal(t, in, stdout.String())
+		for name, want := range map[string]string{"out1": in, "out2": in, "cat": in, "head": "foo"} {
+			got, err := os.ReadFile(filepath.Join(dir, name))
+			assert.NoError(t, err)
+			assert.Equal(t, want, string(got), name)
+		}
+	})
+	t.Run("Input_file", func(t *testing.T) {
+		name := filepath.Join(t.TempDir(), "in")
+		assert.NoError(t, os.WriteFile(name, []byte(in), 0o600))
+		var stdout, stderr bytes.Buffer
+		status := run(context.Background(), []string{"-i", name, "-stdout"}, strings.NewReader("ignored"), &stdout, &stderr)
+		assert.Equal(t, 0, status, stderr.String())
+		assert.Equal(t, in, stdout.String())
+	})
+	t.Run("Failing_branch", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		status := run(context.Background(), []string{"-stdout", "exit 3"}, strings.NewReader(in), &stdout, &stderr)
+		assert.Equal(t, 1, status)
+		assert.Contains(t, stderr.String(), `command "exit 3": exit status 3`)
+		// The other branches still get all input.
+		assert.Equal(t, in, stdout.String())
+	})
+	t.Run("Fail_fast", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		status := run(context.Background(), []string{"-fail-fast", "cat > /dev/null; sleep 10", "exit 3"}, strings.NewReader(in), &stdout, &stderr)
+		assert.Equal(t, 1, status)
+		assert.Contains(t, stderr.String(), `command "exit 3": exit status 3`)
+		assert.NotContains(t, stderr.String(), "sleep")
+	})
+	t.Run("Usage", func(t *testing.T) {
+		for _, args := range [][]string{{}, {"-slow", "foo", "cat"}, {"-foo"}} {
+			var stdout, stderr bytes.Buffer
+			status := run(context.Background(), args, strings.NewReader(in), &stdout, &stderr)
+			assert.Equal(t, 2, status, "args: %q", args)
+			assert.Contains(t, stderr.String(), "Usage: multee", "args: %q", args)
+		}
+	})
+}
@@ -0,0 +1,77 @@
+// Copyright 2023-2025 Roel Harbers.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package multee_test
+
+import (
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/ComaVN/multee"
+	"github.com/ComaVN/multee/multeetest"
+)
+
+func TestConformance(t *testing.T) {
+	t.Run("Default", func(t *testing.T) {
+		multeetest.Run(t, func(inputReader io.Reader) multee.MulteeReader {
+			return multee.NewMulteeReader(inputReader)
+		})
+	})
+	t.Run("With_lookahead", func(t *testing.T) {
+		multeetest.Run(t, func(inputReader io.Reader) multee.MulteeReader {
+			return multee.NewMulteeReader(inputReader, multee.WithChunkSize(1024), multee.WithLookahead(8*1024))
+		})
+	})
+	t.Run("With_spill_to_disk", func(t *testing.T) {
+		multeetest.Run(t, func(inputReader io.Reader) multee.MulteeReader {
+			return multee.NewMulteeReader(inputReader, multee.WithChunkSize(1024), multee.WithSpillToDisk(t.TempDir(), 4096))
+		})
+	})
+	t.Run("Strategy_barrier", func(t *testing.T) {
+		multeetest.Run(t, func(inputReader io.Reader) multee.MulteeReader {
+			return multee.NewMulteeReader(inputReader, multee.WithStrategy(multee.StrategyBarrier), multee.WithLookahead(8*1024))
+		})
+	})
+	t.Run("Strategy_channel", func(t *testing.T) {
+		multeetest.Run(t, func(inputReader io.Reader) multee.MulteeReader {
+			return multee.NewMulteeReader(inputReader, multee.WithStrategy(multee.StrategyChannel), multee.WithChunkSize(1024), multee.WithLookahead(8*1024))
+		})
+	})
+	t.Run("Reader_at", func(t *testing.T) {
+		multeetest.Run(t, func(inputReader io.Reader) multee.MulteeReader {
+			return multee.NewMulteeReaderAt(&lazyReaderAt{r: inputReader}, -1)
+		})
+	})
+}
+
+// This is an io.ReaderAt that reads from an io.Reader as far as needed, so the conformance suite can be run on NewMulteeReaderAt.
+// The error of the io.Reader is returned when reading at or beyond the offset it was returned at.
+// Unlike a proper io.ReaderAt, it returns the bytes that are available without an error, once it has at least one,
+// like the io.Reader would, so short reads from a pipe don't block.
+type lazyReaderAt struct {
+	mu  sync.Mutex
+	r   io.Reader
+	buf []byte
+	err error
+}
+
+func (ra *lazyReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+	for int64(len(ra.buf)) <= off && ra.err == nil {
+		chunk := make([]byte, 4096)
+		n, err := ra.r.Read(chunk)
+		ra.buf = append(ra.buf, chunk[:n]...)
+		ra.err = err
+	}
+	if off >= int64(len(ra.buf)) {
+		return 0, ra.err
+	}
+	n := copy(p, ra.buf[off:])
+	if n < len(p) {
+		return n, ra.err
+	}
+	return n, nil
+}
@@ -0,0 +1,141 @@
+// Copyright 2023-2025 Roel Harbers.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package multee
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"runtime"
+	"runtime/debug"
+	"time"
+)
+
+// Debug configures the debug mode of a multeeReader, see WithDebug.
+type Debug struct {
+	// Report is called with a report of each reader that was garbage collected without being closed,
+	// and of each reader that keeps the other readers waiting for longer than StallThreshold.
+	// Like Hooks, it's called synchronously, while the multeeReader is locked.
+	// A nil Report logs the reports using the log package.
+	Report func(report ReaderReport)
+	// StallThreshold is how long a reader may keep the other readers waiting, before it's reported.
+	// A zero StallThreshold only reports readers that were garbage collected without being closed.
+	StallThreshold time.Duration
+}
+
+// ReaderReport describes a reader that was found by the debug mode of a multeeReader, see WithDebug.
+type ReaderReport struct {
+	Leaked  bool          // This is set when the reader was garbage collected without being closed, it has been closed automatically.
+	Stalled time.Duration // How long the reader has kept the other readers waiting, when it wasn't leaked.
+	Offset  int64         // Absolute offset in the input of the next byte the reader reads.
+	Lag     int64         // Number of bytes read from the input reader, but not by the reader yet.
+	Stack   []byte        // Stack trace of the goroutine that created the reader.
+}
+
+func (rep ReaderReport) String() string {
+	problem := fmt.Sprintf("kept the other readers waiting for %v", rep.Stalled)
+	if rep.Leaked {
+		problem = "was garbage collected without being closed"
+	}
+	return fmt.Sprintf("multee: reader at offset %d, lagging %d bytes, %s; it was created by:\n%s", rep.Offset, rep.Lag, problem, rep.Stack)
+}
+
+// Must be called with mu locked.
+func (mr *multeeReader) report(r *reader, rep ReaderReport) {
+	rep.Offset = r.offset
+	rep.Lag = mr.lag(r)
+	rep.Stack = r.stack
+	if mr.cfg.debug.Report == nil {
+		log.Print(rep)
+		return
+	}
+	mr.cfg.debug.Report(rep)
+}
+
+// Returns r, wrapped so it's closed automatically when it's garbage collected without being closed, in debug mode.
+func (mr *multeeReader) track(r *reader) Reader {
+	if mr.cfg.debug == nil {
+		return r
+	}
+	tr := &trackedReader{r}
+	runtime.SetFinalizer(tr, func(tr *trackedReader) {
+		r := tr.reader
+		if r.stopCtx != nil {
+			r.stopCtx()
+		}
+		mr.mu.Lock()
+		defer mr.mu.Unlock()
+		if r.err == nil && !(mr.err != nil && r.offset == mr.offset) {
+			// The reader was neither closed nor detached, nor read to the end of the input.
+			mr.report(r, ReaderReport{Leaked: true})
+		}
+		mr.detach(r, ErrClosed)
+		r.closed = true
+	})
+	return tr
+}
+
+// Starts a timer, that reports the readers that keep the other readers waiting for longer than the stall threshold.
+// Must be called with mu locked, when a reader is about to wait for a slot in the ring to be freed.
+func (mr *multeeReader) startWatchdog() {
+	if mr.cfg.debug == nil || mr.cfg.debug.StallThreshold <= 0 || mr.watchdogTimer != nil {
+		return
+	}
+	head := mr.head
+	start := time.Now()
+	var timer *time.Timer
+	timer = time.AfterFunc(mr.cfg.debug.StallThreshold, func() {
+		mr.mu.Lock()
+		defer mr.mu.Unlock()
+		if mr.head != head || mr.loading {
+			// The stall is already over.
+			return
+		}
+		if mr.slotWaiters == 0 {
+			// The readers that were waiting have gone, so there is no stall to report.
+			if mr.watchdogTimer == timer {
+				mr.watchdogTimer = nil
+			}
+			return
+		}
+		for r := range mr.readers {
+			if mr.blocking(r) {
+				mr.report(r, ReaderReport{Stalled: time.Since(start)})
+			}
+		}
+	})
+	mr.watchdogTimer = timer
+}
+
+// The Reader returned in debug mode, so the reader it wraps can be closed when this is garbage collected.
+// The reader itself can't be used for that, since the multeeReader refers to it.
+type trackedReader struct {
+	*reader
+}
+
+// These make sure tr isn't garbage collected, and the reader closed, while it's being used.
+
+func (tr *trackedReader) Read(p []byte) (int, error) {
+	defer runtime.KeepAlive(tr)
+	return tr.reader.Read(p)
+}
+
+func (tr *trackedReader) WriteTo(w io.Writer) (int64, error) {
+	defer runtime.KeepAlive(tr)
+	return tr.reader.WriteTo(w)
+}
+
+func (tr *trackedReader) Close() error {
+	runtime.SetFinalizer(tr, nil)
+	return tr.reader.Close()
+}
+
+// Returns the stack trace of the calling goroutine, in debug mode.
+func (mr *multeeReader) stack() []byte {
+	if mr.cfg.debug == nil {
+		return nil
+	}
+	return debug.Stack()
+}
@@ -0,0 +1,145 @@
+// Copyright 2023-2025 Roel Harbers.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package multee
+
+import (
+	"context"
+	"io"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithDebug(t *testing.T) {
+	t.Run("Leaked_reader", func(t *testing.T) {
+		var mu sync.Mutex
+		var reports []ReaderReport
+		mr := NewMulteeReader(strings.NewReader("foobar"), WithChunkSize(1), WithDebug(Debug{
+			Report: func(report ReaderReport) {
+				mu.Lock()
+				defer mu.Unlock()
+				reports = append(reports, report)
+			},
+		}))
+		r := mr.NewReader()
+		defer r.Close()
+		// This reader is never read or closed, so it blocks r, until it's garbage collected.
+		func() { mr.NewReader() }()
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			b, err := io.ReadAll(r)
+			assert.NoError(t, err)
+			assert.Equal(t, []byte("foobar"), b)
+		}()
+		assert.Eventually(t, func() bool {
+			runtime.GC()
+			select {
+			case <-done:
+				return true
+			default:
+				return false
+			}
+		}, 5*time.Second, 10*time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		if assert.Len(t, reports, 1) {
+			assert.True(t, reports[0].Leaked)
+			assert.Equal(t, int64(0), reports[0].Offset)
+			assert.Contains(t, string(reports[0].Stack), "TestWithDebug")
+		}
+	})
+	t.Run("Closed_reader", func(t *testing.T) {
+		reported := false
+		mr := NewMulteeReader(strings.NewReader("foobar"), WithDebug(Debug{
+			Report: func(report ReaderReport) { reported = true },
+		}))
+		func() {
+			r := mr.NewReader()
+			assert.NoError(t, r.Close())
+			r = mr.NewReader()
+			_, err := io.ReadAll(r)
+			assert.NoError(t, err)
+		}()
+		runtime.GC()
+		// Give the finalizers a chance to run.
+		time.Sleep(10 * time.Millisecond)
+		mr.(*multeeReader).mu.Lock()
+		defer mr.(*multeeReader).mu.Unlock()
+		assert.False(t, reported)
+	})
+	t.Run("Stalled_reader", func(t *testing.T) {
+		reports := make(chan ReaderReport, 1)
+		mr := NewMulteeReader(strings.NewReader("foobar"), WithChunkSize(1), WithDebug(Debug{
+			Report: func(report ReaderReport) {
+				select {
+				case reports <- report:
+				default:
+				}
+			},
+			StallThreshold: 10 * time.Millisecond,
+		}))
+		r1 := mr.NewReader()
+		defer r1.Close()
+		r2 := mr.NewReader()
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_, err := io.ReadAll(r1)
+			assert.NoError(t, err)
+		}()
+		select {
+		case report := <-reports:
+			assert.False(t, report.Leaked)
+			assert.GreaterOrEqual(t, report.Stalled, 10*time.Millisecond)
+			assert.Equal(t, int64(0), report.Offset)
+			assert.Equal(t, int64(1), report.Lag)
+		case <-time.After(5 * time.Second):
+			t.Error("stalled reader not reported")
+		}
+		assert.NoError(t, r2.Close())
+		<-done
+	})
+	t.Run("Waiting_reader_canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		reports := make(chan ReaderReport, 1)
+		mr := NewMulteeReader(strings.NewReader("foobar"), WithDebug(Debug{
+			Report: func(report ReaderReport) {
+				select {
+				case reports <- report:
+				default:
+				}
+			},
+			StallThreshold: 10 * time.Millisecond,
+		}))
+		r1 := mr.NewReaderContext(ctx)
+		defer r1.Close()
+		r2 := mr.NewReader()
+		defer r2.Close()
+		_, err := io.ReadFull(r1, make([]byte, 6))
+		assert.NoError(t, err)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			// This waits for r2, until r1 is canceled.
+			_, err := r1.Read(make([]byte, 6))
+			assert.ErrorIs(t, err, context.Canceled)
+		}()
+		time.Sleep(2 * time.Millisecond)
+		cancel()
+		<-done
+		// Nobody is waiting for r2 anymore, so it doesn't stall anyone.
+		select {
+		case report := <-reports:
+			t.Errorf("unexpected report: %v", report)
+		case <-time.After(20 * time.Millisecond):
+		}
+	})
+}
@@ -4,8 +4,83 @@
 
 package multee
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
 
 var (
-	ErrClosed = errors.New("multeeReader already closed")
+	ErrClosed        = errors.New("multeeReader already closed")
+	ErrReaderTooSlow = errors.New("multeeReader reader too slow")
+	ErrInternalState = errors.New("multeeReader internal state corrupted")
+	ErrOffsetEvicted = errors.New("multeeReader offset no longer retained")
+	// ErrConcurrentUse is returned when a reader is used by several goroutines at once, see WithConcurrencyChecks.
+	ErrConcurrentUse = errors.New("multeeReader reader used concurrently")
+	// ErrSource matches all errors returned by the input reader, except io.EOF, which is returned as is.
+	ErrSource = errors.New("multeeReader input reader failed")
+	// ErrSourceTruncated matches io.ErrUnexpectedEOF returned by the input reader.
+	ErrSourceTruncated = errors.New("multeeReader input truncated")
+	// ErrSourceTemporary matches errors returned by the input reader that have a Timeout or Temporary method returning true,
+	// like temporary network errors.
+	ErrSourceTemporary = errors.New("multeeReader input temporarily unavailable")
+	// ErrCanceled matches context errors, both when the context of a multeeReader or reader is done,
+	// and when the input reader returns one.
+	ErrCanceled = errors.New("multeeReader canceled")
 )
+
+// SourceError is returned by reads from readers, when the input reader returned an error other than io.EOF.
+// It matches ErrSource, the original error, and the more specific sentinels above, if any, using errors.Is.
+type SourceError struct {
+	Err    error // The error returned by the input reader.
+	Offset int64 // Stream offset of the input reader when it returned the error.
+}
+
+func (e *SourceError) Error() string {
+	return fmt.Sprintf("%v at offset %d: %v", ErrSource, e.Offset, e.Err)
+}
+
+func (e *SourceError) Unwrap() []error {
+	errs := []error{e.Err, ErrSource}
+	var temporary interface{ Temporary() bool }
+	var timeout interface{ Timeout() bool }
+	switch {
+	case errors.Is(e.Err, io.ErrUnexpectedEOF):
+		errs = append(errs, ErrSourceTruncated)
+	case errors.Is(e.Err, context.Canceled), errors.Is(e.Err, context.DeadlineExceeded):
+		errs = append(errs, ErrCanceled)
+	case errors.As(e.Err, &temporary) && temporary.Temporary(), errors.As(e.Err, &timeout) && timeout.Timeout():
+		errs = append(errs, ErrSourceTemporary)
+	}
+	return errs
+}
+
+// Returns err, wrapped in a SourceError, unless it's nil or io.EOF.
+func sourceError(err error, offset int64) error {
+	if err == nil || err == io.EOF {
+		return err
+	}
+	return &SourceError{Err: err, Offset: offset}
+}
+
+// Returns the context error err, wrapped so it also matches ErrCanceled.
+func canceledError(err error) error {
+	return fmt.Errorf("%w: %w", ErrCanceled, err)
+}
+
+// InternalStateError is returned by all reads from all readers of a multeeReader, when its internal state turns out to be corrupted.
+// It matches ErrInternalState, using errors.Is.
+type InternalStateError struct {
+	Chunk     uint64 // Sequence number of the chunk the reader was reading.
+	BufOffset int    // Offset of the reader in the chunk.
+	EndPos    int    // End of the chunk.
+}
+
+func (e *InternalStateError) Error() string {
+	return fmt.Sprintf("%v: reader buffer offset (%d) is beyond buffer end (%d) of chunk %d", ErrInternalState, e.BufOffset, e.EndPos, e.Chunk)
+}
+
+func (e *InternalStateError) Unwrap() error {
+	return ErrInternalState
+}
@@ -0,0 +1,51 @@
+// Copyright 2023-2025 Roel Harbers.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package multee
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string { return "timeout" }
+func (timeoutError) Timeout() bool { return true }
+
+func TestSourceError(t *testing.T) {
+	errFoo := errors.New("foo")
+	sentinels := []error{ErrSourceTruncated, ErrSourceTemporary, ErrCanceled}
+	for _, tc := range []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"Other", errFoo, nil},
+		{"Unexpected_EOF", io.ErrUnexpectedEOF, ErrSourceTruncated},
+		{"Timeout", timeoutError{}, ErrSourceTemporary},
+		{"Context_canceled", context.Canceled, ErrCanceled},
+		{"Context_deadline_exceeded", context.DeadlineExceeded, ErrCanceled},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := sourceError(tc.err, 3)
+			assert.ErrorIs(t, err, tc.err)
+			assert.ErrorIs(t, err, ErrSource)
+			for _, sentinel := range sentinels {
+				if sentinel == tc.want {
+					assert.ErrorIs(t, err, sentinel)
+				} else {
+					assert.NotErrorIs(t, err, sentinel)
+				}
+			}
+		})
+	}
+	t.Run("EOF", func(t *testing.T) {
+		assert.Equal(t, io.EOF, sourceError(io.EOF, 3))
+	})
+}
@@ -0,0 +1,29 @@
+// Copyright 2023-2025 Roel Harbers.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package multee
+
+import "sync/atomic"
+
+// Detects overlapping calls on a single reader, when concurrency checks are enabled, see WithConcurrencyChecks.
+type useGuard struct {
+	checked bool
+	inUse   atomic.Bool
+}
+
+// Must be called at the start of each Read, WriteTo or Close of a reader.
+// Returns ErrConcurrentUse when the reader is already in use, in which case release must not be called.
+func (g *useGuard) acquire() error {
+	if g.checked && !g.inUse.CompareAndSwap(false, true) {
+		return ErrConcurrentUse
+	}
+	return nil
+}
+
+// Must be called at the end of each Read, WriteTo or Close of a reader, if acquire succeeded.
+func (g *useGuard) release() {
+	if g.checked {
+		g.inUse.Store(false)
+	}
+}
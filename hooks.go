@@ -0,0 +1,91 @@
+// Copyright 2023-2025 Roel Harbers.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package multee
+
+// Observer gets called on events in a multeeReader, see WithObserver.
+// Its methods are called synchronously, while the multeeReader is locked,
+// so they must return quickly, and must not use the multeeReader or any of its readers.
+type Observer interface {
+	// OnChunkLoad is called after each read from the input reader, with its results.
+	OnChunkLoad(n int, err error)
+	// OnReaderJoin is called when a reader is added.
+	OnReaderJoin()
+	// OnReaderClose is called when a reader is closed or detached, with the error its reads return from then on.
+	OnReaderClose(err error)
+	// OnError is called when the input reader returns an error other than io.EOF,
+	// or when the multeeReader fails, with the error all reads of all readers return from then on.
+	OnError(err error)
+}
+
+// Hooks are functions that are called on events in a multeeReader. Any of them may be nil.
+// Like the methods of an Observer, they are called synchronously, while the multeeReader is locked,
+// so they must return quickly, and must not use the multeeReader or any of its readers.
+type Hooks struct {
+	// OnChunkLoad is called after each read from the input reader, with its results.
+	OnChunkLoad func(n int, err error)
+	// OnReaderJoin is called when a reader is added.
+	OnReaderJoin func()
+	// OnReaderClose is called when a reader is closed or detached, with the error its reads return from then on.
+	OnReaderClose func(err error)
+	// OnError is called when the input reader returns an error other than io.EOF,
+	// or when the multeeReader fails, with the error all reads of all readers return from then on.
+	OnError func(err error)
+}
+
+// Adapts Hooks to the Observer interface.
+type hooksObserver struct {
+	hooks Hooks
+}
+
+func (h hooksObserver) OnChunkLoad(n int, err error) {
+	if h.hooks.OnChunkLoad != nil {
+		h.hooks.OnChunkLoad(n, err)
+	}
+}
+
+func (h hooksObserver) OnReaderJoin() {
+	if h.hooks.OnReaderJoin != nil {
+		h.hooks.OnReaderJoin()
+	}
+}
+
+func (h hooksObserver) OnReaderClose(err error) {
+	if h.hooks.OnReaderClose != nil {
+		h.hooks.OnReaderClose(err)
+	}
+}
+
+func (h hooksObserver) OnError(err error) {
+	if h.hooks.OnError != nil {
+		h.hooks.OnError(err)
+	}
+}
+
+// All observers of a multeeReader, including its Hooks.
+type observers []Observer
+
+func (obs observers) chunkLoad(n int, err error) {
+	for _, o := range obs {
+		o.OnChunkLoad(n, err)
+	}
+}
+
+func (obs observers) readerJoin() {
+	for _, o := range obs {
+		o.OnReaderJoin()
+	}
+}
+
+func (obs observers) readerClose(err error) {
+	for _, o := range obs {
+		o.OnReaderClose(err)
+	}
+}
+
+func (obs observers) error(err error) {
+	for _, o := range obs {
+		o.OnError(err)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"math/rand"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/ComaVN/multee"
 )
@@ -129,3 +130,69 @@ func Test_multee_monkeytest(t *testing.T) {
 		})
 	}
 }
+
+func Test_multee_late_joiners(t *testing.T) {
+	const (
+		NumberOfSeeds    = 5   // Run the test with this many different, predictable seeds.
+		NumberOfReaders  = 20  // Add this many readers, at random moments while the stream is being read.
+		MaxJoinDelay     = 10  // Wait at most this many milliseconds before adding a reader.
+		MaxNumberOfReads = 200 // Read at most this many uint64's before closing a reader.
+		MaxLookahead     = 4   // Use a ring of at most this many chunks.
+	)
+	for rndSeed := int64(0); rndSeed < NumberOfSeeds; rndSeed++ {
+		t.Run(fmt.Sprintf("Late_joiners_with_rnd_seed_%d", rndSeed), func(t *testing.T) {
+			rnd := rand.New(rand.NewSource(rndSeed))
+			inputR, inputW := io.Pipe()
+			go func(rndSeed int64) {
+				// Generate an infinite stream of bytes, each 8 bytes containing their 64-bit offset in the stream.
+				// Since each write is read as a separate chunk, every chunk boundary is at a multiple of 8 bytes.
+				buf := make([]byte, 8)
+				offs := uint64(0)
+				for {
+					binary.LittleEndian.PutUint64(buf, offs)
+					l, err := inputW.Write(buf)
+					if l != len(buf) || err != nil {
+						panic(fmt.Errorf("rnd seed %d: failed to write the full uint64 (%d, only %d bytes written, err: %v)", rndSeed, offs, l, err))
+					}
+					offs += uint64(l)
+				}
+			}(rndSeed)
+			opts := []multee.Option{multee.WithLookahead((1 + rnd.Intn(MaxLookahead)) * 32 * 1024)}
+			if rnd.Intn(2) == 0 {
+				opts = append(opts, multee.WithSpillToDisk(t.TempDir(), 4096))
+			}
+			mr := multee.NewMulteeReader(inputR, opts...)
+			var wg sync.WaitGroup
+			wg.Add(NumberOfReaders)
+			for rdrIdx := 0; rdrIdx < NumberOfReaders; rdrIdx++ {
+				joinDelay := time.Duration(rnd.Intn(MaxJoinDelay+1)) * time.Millisecond
+				joinPos := multee.JoinPosition(rnd.Intn(2))
+				numReads := 1 + rnd.Intn(MaxNumberOfReads)
+				go func(rndSeed int64, rdrIdx int) {
+					// This joins at a random moment, reads a random number of uint64's,
+					// and checks if they are consecutive offsets, as the writer wrote them.
+					defer wg.Done()
+					time.Sleep(joinDelay)
+					r := mr.NewReaderJoining(joinPos)
+					defer r.Close()
+					buf := make([]byte, 8)
+					var offs uint64
+					for i := 0; i < numReads; i++ {
+						l, err := io.ReadFull(r, buf)
+						if l != len(buf) || err != nil {
+							t.Errorf("rnd seed %d, reader index: %d: failed to read the full buffer (%d bytes read, err: %v)", rndSeed, rdrIdx, l, err)
+							return
+						}
+						got := binary.LittleEndian.Uint64(buf)
+						if i > 0 && got != offs {
+							t.Errorf("rnd seed %d, reader index: %d: expected to read offset %d, got %d", rndSeed, rdrIdx, offs, got)
+							return
+						}
+						offs = got + 8
+					}
+				}(rndSeed, rdrIdx)
+			}
+			wg.Wait()
+		})
+	}
+}
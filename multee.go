@@ -7,91 +7,668 @@
 package multee
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"sync"
-	"sync/atomic"
+	"time"
 )
 
-const bufferSize = 32 * 1024
+const bufferSize = 32 * 1024 // The default chunk size.
+
+// JoinPosition determines where a reader starts reading, when it is added to a multeeReader that is already being read from.
+type JoinPosition int
+
+const (
+	// JoinNextChunk makes the reader start at the first byte of the next chunk loaded from the input reader.
+	JoinNextChunk JoinPosition = iota
+	// JoinCurrentChunk makes the reader start at the first byte of the last chunk loaded from the input reader.
+	// If that chunk is already being replaced by the next chunk, the reader starts at the next chunk instead.
+	JoinCurrentChunk
+)
+
+// MulteeReader multiplexes a single input reader to any number of readers.
+// It's implemented by this package, and by the alternative implementations in the alt directory,
+// so they can be used interchangeably.
+type MulteeReader interface {
+	// Returns a new reader, that starts at the next chunk boundary.
+	NewReader() Reader
+	// Returns a new reader, that starts at the given position.
+	NewReaderJoining(pos JoinPosition) Reader
+	// Returns a new reader, that's detached from the MulteeReader when ctx is done.
+	NewReaderContext(ctx context.Context) Reader
+	// Returns a new reader, that starts at the given absolute offset in the input, if it's still retained.
+	NewReaderFrom(offset int64) (Reader, error)
+	// Returns the number of bytes read from the input reader so far.
+	SourceOffset() int64
+	// Returns the current statistics of the MulteeReader.
+	Stats() Stats
+}
+
+// Reader is a reader returned by a MulteeReader.
+// It must either be read until EOF or closed, or the MulteeReader will block.
+type Reader interface {
+	io.ReadCloser
+	// Returns the absolute offset in the input of the next byte this reader reads.
+	Offset() int64
+	// Returns the number of bytes that have been read from the input reader, but not by this reader yet.
+	Lag() int64
+	// Returns the current statistics of the reader.
+	Stats() ReaderStats
+}
+
+var _ io.WriterTo = (*reader)(nil)
 
 type multeeReader struct {
-	inputReader      io.Reader
-	err              error
-	buf              [bufferSize]byte
-	bufEndPos        int
-	bufReadOnce      *sync.Once     // This makes sure only a single reader will load the next buffer.
-	bufReadWaitGroup sync.WaitGroup // This waits for all current readers to be finished with reading the current buffer (or closed).
-	readerCnt        atomic.Int32
+	inputReader io.Reader
+	cfg         config
+	mu          sync.Mutex // This guards all fields below, and the chunk, bufOffset and err fields of all readers.
+	cond        *sync.Cond // This is broadcast whenever a chunk has been loaded, or a slot in the ring may have been freed.
+	err         error
+	ring        []chunk // Ring of chunks, chunk with sequence number seq is kept in ring[seq % len(ring)], unless it has been spilled.
+	spill       *spill  // This is only set when spilling to disk is enabled.
+	head        uint64  // Sequence number of the last loaded chunk. Chunk 0 is the empty chunk before the first input read.
+	offset      int64   // Number of bytes read from the input reader.
+	loading     bool    // This is set while a reader is loading the next chunk into the ring, with mu unlocked.
+	slotWaiters int     // Number of readers waiting for a slot in the ring to be freed.
+	readers     map[*reader]struct{}
+	abortErr    error       // When set, all reads from all readers return this error, eg. when the multeeReader's context is done.
+	stallTimer  *time.Timer // This detects readers that stall the ring for too long.
+	// This reports readers that stall the ring for too long, in debug mode.
+	watchdogTimer *time.Timer
+}
+
+// A chunk of input bytes, as read by a single read from the input reader.
+type chunk struct {
+	buf         []byte // This is allocated when the slot is first used, and reused for every chunk in the same slot.
+	startOffset int64  // Stream offset of the first byte in buf.
+	endPos      int
+	pins        int // Number of readers using buf with mu unlocked, see each.
+}
+
+// The MulteeReader implementations of this package, see WithStrategy.
+type multeeImpl interface {
+	MulteeReader
+	// Makes all current and future reads from all readers return err.
+	fail(err error)
+}
+
+var (
+	_ multeeImpl = (*multeeReader)(nil)
+	_ multeeImpl = (*chanMulteeReader)(nil)
+)
+
+// Returns a multeeReader for inputReader, configured by opts.
+// Without options, the input is read in chunks of 32 KiB, and all readers read the same chunk in lockstep.
+func NewMulteeReader(inputReader io.Reader, opts ...Option) MulteeReader {
+	return newMulteeImpl(inputReader, opts)
+}
+
+// Returns the implementation selected by the strategy in opts.
+func newMulteeImpl(inputReader io.Reader, opts []Option) multeeImpl {
+	cfg := newConfig(opts)
+	if cfg.strategy == StrategyChannel {
+		return newChanMulteeReader(inputReader, cfg)
+	}
+	return newMulteeReaderConfig(inputReader, cfg)
+}
+
+func newMulteeReader(inputReader io.Reader, opts ...Option) *multeeReader {
+	return newMulteeReaderConfig(inputReader, newConfig(opts))
 }
 
-func NewMulteeReader(inputReader io.Reader) *multeeReader {
-	return &multeeReader{
+func newMulteeReaderConfig(inputReader io.Reader, cfg config) *multeeReader {
+	mr := &multeeReader{
 		inputReader: inputReader,
-		bufReadOnce: new(sync.Once),
+		cfg:         cfg,
+		ring:        make([]chunk, cfg.ringSize()),
+		readers:     make(map[*reader]struct{}),
 	}
+	if cfg.spill {
+		mr.spill = &spill{
+			dir:         cfg.spillDir,
+			segmentSize: cfg.spillSegmentSize,
+		}
+	}
+	mr.cond = sync.NewCond(&mr.mu)
+	return mr
+}
+
+// Like NewMulteeReader, but when ctx is done, all current and future reads from all its readers return an error
+// matching both ctx.Err() and ErrCanceled.
+// Note that a read that is blocked on the input reader itself can't be interrupted,
+// but all reads waiting for other readers, or for that read, are.
+func NewMulteeReaderContext(ctx context.Context, inputReader io.Reader, opts ...Option) MulteeReader {
+	return newMulteeReaderContext(ctx, inputReader, opts...)
+}
+
+func newMulteeReaderContext(ctx context.Context, inputReader io.Reader, opts ...Option) multeeImpl {
+	mr := newMulteeImpl(inputReader, opts)
+	context.AfterFunc(ctx, func() {
+		mr.fail(canceledError(ctx.Err()))
+	})
+	return mr
 }
 
 // Returns an io.ReadCloser. The caller must either keep reading until EOF or call Close(),
 // or the MulteeReader will block.
 // The returned reader is *not* concurrency-safe.
 // Of course, it *is* safe to use multiple readers from the same multeeReader in different goroutines.
-// TODO: at the moment, adding new readers to an existing multeeReader that's being read from by other readers is *not* safe.
-// This probably should either be made safe or, more likely, impossible.
-func (mr *multeeReader) NewReader() *reader {
-	mr.readerCnt.Add(1)
-	mr.bufReadWaitGroup.Add(1)
-	return &reader{
+// It is also safe to call NewReader while other readers are reading, the new reader will start at the next chunk boundary.
+func (mr *multeeReader) NewReader() Reader {
+	return mr.track(mr.newReader(JoinNextChunk))
+}
+
+// Like NewReader, but lets the caller choose where the new reader starts reading.
+func (mr *multeeReader) NewReaderJoining(pos JoinPosition) Reader {
+	return mr.track(mr.newReader(pos))
+}
+
+func (mr *multeeReader) newReader(pos JoinPosition) *reader {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	if pos == JoinCurrentChunk && mr.head > 0 && !(mr.loading && len(mr.ring) == 1) {
+		// The last loaded chunk can't be replaced while this reader hasn't finished it.
+		return mr.addReader(mr.head, 0, mr.chunk(mr.head).startOffset)
+	}
+	return mr.addReader(mr.head+1, 0, mr.offset)
+}
+
+// Like NewReader, but the reader starts at the given absolute offset in the input, which may be before the offset
+// the other readers are at, as long as it is still retained, see WithRetention. Otherwise, ErrOffsetEvicted is returned.
+// The offset of the next byte read from the input reader is always valid.
+func (mr *multeeReader) NewReaderFrom(offset int64) (Reader, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	if offset > mr.offset {
+		return nil, fmt.Errorf("offset %d is beyond the input read so far (%d bytes)", offset, mr.offset)
+	}
+	if offset == mr.offset {
+		return mr.track(mr.addReader(mr.head+1, 0, offset)), nil
+	}
+	for seq := mr.head; seq > 0 && mr.retained(seq); seq-- {
+		startOffset, endPos := mr.chunk(seq).startOffset, mr.chunk(seq).endPos
+		if sc := mr.spill.chunk(seq); sc != nil {
+			startOffset, endPos = sc.startOffset, sc.endPos
+		}
+		if offset >= startOffset && offset < startOffset+int64(endPos) {
+			return mr.track(mr.addReader(seq, int(offset-startOffset), offset)), nil
+		}
+	}
+	return nil, fmt.Errorf("%w: offset %d", ErrOffsetEvicted, offset)
+}
+
+// Returns whether the chunk with the given sequence number is still in the ring, or spilled to disk.
+// Must be called with mu locked.
+func (mr *multeeReader) retained(seq uint64) bool {
+	if mr.spill.chunk(seq) != nil {
+		return true
+	}
+	oldest := mr.head + 1 - min(mr.head, uint64(len(mr.ring)))
+	if mr.loading {
+		// The oldest chunk is being replaced by the next chunk.
+		oldest++
+	}
+	return seq >= oldest
+}
+
+// Adds a reader, that starts reading at bufOffset in the chunk with the given sequence number, which is at offset in the input.
+// Must be called with mu locked.
+func (mr *multeeReader) addReader(seq uint64, bufOffset int, offset int64) *reader {
+	r := &reader{
 		multeeReader: mr,
+		chunk:        seq,
+		bufOffset:    bufOffset,
+		offset:       offset,
+		stack:        mr.stack(),
 	}
+	r.guard.checked = mr.cfg.checked
+	mr.readers[r] = struct{}{}
+	mr.cfg.observers.readerJoin()
+	return r
+}
+
+// Like NewReader, but when ctx is done, the reader is detached from the multeeReader, as if it was closed,
+// and all current and future reads from it return an error matching both ctx.Err() and ErrCanceled.
+// The reader must still be closed.
+func (mr *multeeReader) NewReaderContext(ctx context.Context) Reader {
+	r := mr.newReader(JoinNextChunk)
+	r.stopCtx = context.AfterFunc(ctx, func() {
+		mr.mu.Lock()
+		defer mr.mu.Unlock()
+		mr.detach(r, canceledError(ctx.Err()))
+	})
+	return mr.track(r)
 }
 
 // Used internally by reader to read buffered input bytes while keeping track of position.
-// Returns the new buffer offset, the number of bytes read, and an error, if any.
-func (mr *multeeReader) read(p []byte, bufOffset int) (int, int, error) {
-	if bufOffset == mr.bufEndPos {
-		// The current buffer is empty, or has been fully read by the calling reader.
-		once := mr.bufReadOnce // This needs to be first, to prevent a race condition when the new "once" is created.
-		mr.bufReadWaitGroup.Done()
-		once.Do(func() {
-			// Let the first reader to get here wait for the other readers, and buffer the next input block.
-			// This means no-one else is accessing mr.buf, mr.bufEndPos, mr.inputReader or mr.err.
-			// This is concurrency-safe as long as no readers are added while waiting here.
-			mr.bufReadWaitGroup.Wait()
-			mr.bufReadWaitGroup.Add(int(mr.readerCnt.Load()))
-			mr.bufEndPos, mr.err = mr.inputReader.Read(mr.buf[:])
-			mr.bufReadOnce = new(sync.Once)
-		})
-		bufOffset = 0
+// Returns the number of bytes read, and an error, if any.
+func (mr *multeeReader) read(r *reader, p []byte) (int, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	r.stats.Reads++
+	c, sc, err := mr.next(r)
+	if err != nil {
+		return 0, err
 	}
-	if bufOffset > mr.bufEndPos {
-		// RH: ATTN: This should be impossible.
-		panic(fmt.Errorf("reader buffer offset (%d) is beyond buffer end (%d)", bufOffset, mr.bufEndPos))
+	if sc != nil {
+		n, err := mr.readSpilled(r, sc, p)
+		r.advance(n)
+		return n, err
 	}
 	// Copy the remaining part of the buffer, or the size of p, whichever is smaller
-	copied := copy(p, mr.buf[bufOffset:mr.bufEndPos])
-	return bufOffset + copied, copied, mr.err
+	copied := copy(p, c.buf[r.bufOffset:c.endPos])
+	r.advance(copied)
+	return copied, mr.drainedErr(r, c)
+}
+
+// Used internally by reader to write buffered input bytes to w, until EOF or an error.
+// The bytes in the ring are written directly from the chunk buffers, only spilled chunks are copied.
+// Returns the number of bytes written, and an error, if any, but not io.EOF.
+func (mr *multeeReader) writeTo(r *reader, w io.Writer) (int64, error) {
+	return mr.each(r, func(buf []byte) (int, error) {
+		n, err := w.Write(buf)
+		if err == nil && n < len(buf) {
+			err = io.ErrShortWrite
+		}
+		return n, err
+	})
+}
+
+// Used internally by reader to pass buffered input bytes to f, until EOF or an error.
+// f is called with mu unlocked, with the remaining bytes of each chunk, directly from the chunk buffers,
+// only spilled chunks are copied. It returns how many of those bytes it consumed, and an error, if any.
+// Returns the number of bytes consumed, and an error, if any, but not io.EOF.
+func (mr *multeeReader) each(r *reader, f func(buf []byte) (int, error)) (int64, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	r.stats.Reads++
+	var consumed int64
+	var spillBuf []byte
+	for {
+		c, sc, err := mr.next(r)
+		if err == io.EOF {
+			return consumed, nil
+		}
+		if err != nil {
+			return consumed, err
+		}
+		if sc != nil {
+			// Spilled chunks have to be read from disk anyway.
+			if spillBuf == nil {
+				spillBuf = make([]byte, mr.cfg.chunkSize)
+			}
+			n, err := mr.readSpilled(r, sc, spillBuf)
+			if err != nil {
+				return consumed, err
+			}
+			var m int
+			mr.unlocked(func() {
+				m, err = f(spillBuf[:n])
+			})
+			if err == nil && r.err != nil {
+				// The reader was detached while f was running.
+				return consumed, r.err
+			}
+			// Like for the chunks in the ring, the reader only moves past the bytes f consumed.
+			r.advance(m)
+			consumed += int64(m)
+			if err != nil {
+				return consumed, err
+			}
+			continue
+		}
+		buf := c.buf[r.bufOffset:c.endPos]
+		var n int
+		mr.pinned(c, func() {
+			n, err = f(buf)
+		})
+		if err == nil && r.err != nil {
+			// The reader was detached while f was running.
+			return consumed, r.err
+		}
+		r.advance(n)
+		consumed += int64(n)
+		if err == nil {
+			err = mr.drainedErr(r, c)
+		}
+		if err == io.EOF {
+			return consumed, nil
+		}
+		if err != nil {
+			return consumed, err
+		}
+	}
+}
+
+// Waits until there are bytes for the given reader to read in its current chunk, loading the next chunk if needed.
+// Returns either that chunk, or the spilled chunk if it has been spilled, or an error.
+// Must be called with mu locked.
+func (mr *multeeReader) next(r *reader) (*chunk, *spilledChunk, error) {
+	for {
+		if r.err != nil {
+			return nil, nil, r.err
+		}
+		if mr.abortErr != nil {
+			return nil, nil, mr.abortErr
+		}
+		if r.chunk <= mr.head {
+			c, sc := mr.chunk(r.chunk), mr.spill.chunk(r.chunk)
+			endPos := c.endPos
+			if sc != nil {
+				endPos = sc.endPos
+			}
+			if r.bufOffset > endPos {
+				// This should be impossible, but if it happens, none of the readers can be trusted anymore.
+				mr.abort(&InternalStateError{Chunk: r.chunk, BufOffset: r.bufOffset, EndPos: endPos})
+				continue
+			}
+			if r.bufOffset < endPos {
+				return c, sc, nil
+			}
+			// The chunk is empty, or has been fully read by the calling reader.
+			if r.chunk == mr.head && mr.err != nil {
+				return nil, nil, mr.err
+			}
+			r.chunk++
+			r.bufOffset = 0
+			if mr.slotWaiters > 0 {
+				// This reader may have been the last one holding up the ring.
+				mr.cond.Broadcast()
+			}
+			if sc != nil {
+				mr.releaseSpilled()
+			}
+			continue
+		}
+		// The calling reader has read all loaded chunks.
+		if mr.err != nil {
+			// This reader joined after the input reader returned an error, which is final.
+			return nil, nil, mr.err
+		}
+		if mr.loading || mr.inputReader == nil {
+			// Wait for the next chunk to be loaded, or written by a multeeWriter.
+			mr.wait(r)
+			continue
+		}
+		if mr.spill == nil && !mr.slotFree() {
+			if mr.checkSlowReaders() {
+				// Some readers were too slow, the state may have changed.
+				continue
+			}
+			// Wait for the slowest readers to free up a slot in the ring.
+			mr.slotWaiters++
+			mr.wait(r)
+			mr.slotWaiters--
+			continue
+		}
+		// Let the first reader to get here buffer the next input block.
+		mr.load()
+	}
+}
+
+// Waits for the multeeReader's cond, keeping track of the time the given reader is blocked.
+// Must be called with mu locked.
+func (mr *multeeReader) wait(r *reader) {
+	start := time.Now()
+	mr.cond.Wait()
+	r.stats.Blocked += time.Since(start)
+}
+
+// Returns the error of the input reader, if the given reader has just read all bytes before it.
+// The error is only returned once the reader has read all those bytes, since other readers may still be reading the chunk.
+// Must be called with mu locked.
+func (mr *multeeReader) drainedErr(r *reader, c *chunk) error {
+	if r.chunk == mr.head && r.bufOffset == c.endPos {
+		return mr.err
+	}
+	return nil
+}
+
+// Returns the chunk with the given sequence number, which must still be in the ring.
+// Must be called with mu locked.
+func (mr *multeeReader) chunk(seq uint64) *chunk {
+	return &mr.ring[seq%uint64(len(mr.ring))]
+}
+
+// Returns whether the slot for the next chunk is no longer needed by any reader.
+// Must be called with mu locked.
+func (mr *multeeReader) slotFree() bool {
+	for r := range mr.readers {
+		if mr.blocking(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns whether the given reader still needs the chunk in the slot for the next chunk.
+// Must be called with mu locked.
+func (mr *multeeReader) blocking(r *reader) bool {
+	return r.chunk+uint64(len(mr.ring)) <= mr.head+1
+}
+
+// Loads the next chunk from the input reader into the ring.
+// Must be called with mu locked, and only when no reader needs the chunk in its slot anymore, unless spilling to disk is enabled.
+// Since loading is set, no-one else is accessing that slot, inputReader or err while mu is unlocked.
+// If the input reader panics, loading is reset, so the other readers can still load the chunk.
+func (mr *multeeReader) load() {
+	defer func() {
+		if mr.loading {
+			// Loading was interrupted by a panic.
+			mr.loading = false
+			mr.stopStallTimer()
+			mr.cond.Broadcast()
+		}
+	}()
+	c := mr.startLoad()
+	if c == nil {
+		return
+	}
+	var n int
+	var err error
+	mr.unlocked(func() {
+		n, err = mr.inputReader.Read(c.buf)
+	})
+	mr.finishLoad(c, n, err)
+}
+
+// Like unlocked, but pins c while f is running, so c can't be overwritten, even if the reader reading it is detached
+// in the meantime. c is unpinned again, even when f panics.
+// Must be called with mu locked.
+func (mr *multeeReader) pinned(c *chunk, f func()) {
+	c.pins++
+	defer func() {
+		c.pins--
+		if c.pins == 0 && mr.loading {
+			mr.cond.Broadcast()
+		}
+	}()
+	mr.unlocked(f)
+}
+
+// Calls f with mu unlocked, and locks mu again afterwards, even when f panics,
+// so the deferred unlocks of the callers don't unlock an unlocked mutex.
+// Must be called with mu locked.
+func (mr *multeeReader) unlocked(f func()) {
+	mr.mu.Unlock()
+	defer mr.mu.Lock()
+	f()
+}
+
+// Sets loading, and prepares the slot for the next chunk to be loaded into, spilling the chunk in it to disk if needed.
+// Returns the chunk in that slot, or nil if the multeeReader has been aborted.
+// Must be called with mu locked, under the same conditions as load.
+func (mr *multeeReader) startLoad() *chunk {
+	seq := mr.head + 1
+	c := mr.chunk(seq)
+	if c.buf == nil {
+		c.buf = make([]byte, mr.cfg.chunkSize)
+	}
+	mr.loading = true
+	mr.releaseSpilled()
+	if !mr.slotFree() {
+		// Some readers still need the chunk in the slot, so it has to be spilled to disk first.
+		if err := mr.spillChunk(seq - uint64(len(mr.ring))); err != nil {
+			mr.abort(fmt.Errorf("spilling to disk: %w", err))
+			mr.loading = false
+			return nil
+		}
+	}
+	for c.pins > 0 {
+		// Wait for readers that are still writing the chunk in the slot.
+		mr.cond.Wait()
+	}
+	return c
+}
+
+// Makes the n bytes loaded into c the next chunk, and err the error of the input reader.
+// Must be called with mu locked, after startLoad.
+func (mr *multeeReader) finishLoad(c *chunk, n int, err error) {
+	c.startOffset, c.endPos = mr.offset, n
+	mr.offset += int64(n)
+	mr.err = sourceError(err, mr.offset)
+	mr.head++
+	mr.cfg.observers.chunkLoad(n, err)
+	if mr.err != nil && mr.err != io.EOF {
+		mr.cfg.observers.error(mr.err)
+	}
+	mr.loading = false
+	mr.stopStallTimer()
+	mr.cond.Broadcast()
+}
+
+// Removes a reader from the multeeReader, so the other readers no longer wait for it.
+// All reads from the reader will return err from now on.
+// Must be called with mu locked.
+func (mr *multeeReader) detach(r *reader, err error) {
+	if r.err != nil {
+		// Already detached.
+		return
+	}
+	r.err = err
+	delete(mr.readers, r)
+	if mr.slotWaiters == 0 {
+		// Nobody is waiting for the slow readers anymore.
+		mr.stopStallTimer()
+	}
+	mr.releaseSpilled()
+	mr.cfg.observers.readerClose(err)
+	// This also wakes up the reader itself, if it's waiting.
+	mr.cond.Broadcast()
+}
+
+// Like abort, but locks mu.
+func (mr *multeeReader) fail(err error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	mr.abort(err)
+}
+
+// Makes all current and future reads from all readers return err.
+// Must be called with mu locked.
+func (mr *multeeReader) abort(err error) {
+	if mr.abortErr != nil {
+		return
+	}
+	mr.abortErr = err
+	mr.cfg.observers.error(err)
+	mr.stopStallTimer()
+	mr.cond.Broadcast()
+}
+
+// Returns the number of bytes that have been read from the input reader, but not by the given reader yet.
+// Must be called with mu locked.
+func (mr *multeeReader) lag(r *reader) int64 {
+	return mr.offset - r.offset
+}
+
+// Returns the number of bytes read from the input reader so far.
+func (mr *multeeReader) SourceOffset() int64 {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	return mr.offset
 }
 
 // This is the io.ReadCloser returned by multiReaders.NewReader
 type reader struct {
 	multeeReader *multeeReader
+	chunk        uint64 // Sequence number of the chunk this reader is reading, or waiting for.
 	bufOffset    int
+	offset       int64 // Absolute offset in the input of the next byte this reader reads.
+	stats        ReaderStats
+	err          error       // This is set when the reader has been detached from the multeeReader, or it was too slow.
+	stopCtx      func() bool // This stops detaching the reader when its context is done, if it has one.
 	closed       bool
+	stack        []byte // Stack trace of the goroutine that created the reader, in debug mode.
+	guard        useGuard
 }
 
 func (r *reader) Read(p []byte) (n int, err error) {
-	r.bufOffset, n, err = r.multeeReader.read(p, r.bufOffset)
-	return n, err
+	if err := r.guard.acquire(); err != nil {
+		return 0, err
+	}
+	defer r.guard.release()
+	if r.closed {
+		return 0, ErrClosed
+	}
+	return r.multeeReader.read(r, p)
+}
+
+// Moves the reader n bytes forward in its chunk.
+// Must be called with mu locked.
+func (r *reader) advance(n int) {
+	r.bufOffset += n
+	r.offset += int64(n)
+	r.stats.Bytes += int64(n)
+}
+
+// WriteTo implements io.WriterTo, so io.Copy writes the buffered input bytes directly to w,
+// without copying them to an intermediate buffer first.
+func (r *reader) WriteTo(w io.Writer) (n int64, err error) {
+	if err := r.guard.acquire(); err != nil {
+		return 0, err
+	}
+	defer r.guard.release()
+	if r.closed {
+		return 0, ErrClosed
+	}
+	return r.multeeReader.writeTo(r, w)
+}
+
+// Returns the absolute offset in the input of the next byte this reader reads.
+// Unlike Read, this is concurrency-safe, and it can be called after Close.
+func (r *reader) Offset() int64 {
+	mr := r.multeeReader
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	return r.offset
+}
+
+// Returns the number of bytes that have been read from the input reader, but not by this reader yet.
+// Like Offset, this is concurrency-safe.
+func (r *reader) Lag() int64 {
+	mr := r.multeeReader
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	return mr.lag(r)
 }
 
 func (r *reader) Close() error {
+	if err := r.guard.acquire(); err != nil {
+		return err
+	}
+	defer r.guard.release()
 	if r.closed {
 		return ErrClosed
 	}
-	// readerCnt must be decremented before the wait group, because the readerCnt is used to increment the wait group again.
-	r.multeeReader.readerCnt.Add(-1)
-	r.multeeReader.bufReadWaitGroup.Done()
+	if r.stopCtx != nil {
+		r.stopCtx()
+	}
+	mr := r.multeeReader
+	mr.mu.Lock()
+	mr.detach(r, ErrClosed)
+	mr.mu.Unlock()
 	r.closed = true
 	return nil
 }
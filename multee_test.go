@@ -5,37 +5,350 @@
 package multee
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"io"
 	"strings"
 	"sync"
 	"testing"
+	"testing/iotest"
 
 	"github.com/stretchr/testify/assert"
 )
 
 func TestNewMulteeReader(t *testing.T) {
-	mr := NewMulteeReader(strings.NewReader("foo"))
-	assert.Equal(t, 0, mr.bufEndPos)
-	assert.Equal(t, bufferSize, len(mr.buf))
+	t.Run("Defaults", func(t *testing.T) {
+		mr := newMulteeReader(strings.NewReader("foo"))
+		assert.Equal(t, uint64(0), mr.head)
+		assert.Equal(t, 1, len(mr.ring))
+	})
+	t.Run("With_lookahead", func(t *testing.T) {
+		mr := newMulteeReader(strings.NewReader("foo"), WithLookahead(3*bufferSize+1))
+		assert.Equal(t, uint64(0), mr.head)
+		assert.Equal(t, 4, len(mr.ring))
+	})
+	t.Run("With_chunk_size", func(t *testing.T) {
+		mr := newMulteeReader(strings.NewReader("foo"), WithChunkSize(4096), WithLookahead(3*4096))
+		assert.Equal(t, 4096, mr.cfg.chunkSize)
+		assert.Equal(t, 3, len(mr.ring))
+	})
+	t.Run("With_max_memory", func(t *testing.T) {
+		mr := newMulteeReader(strings.NewReader("foo"), WithLookahead(8*bufferSize), WithMaxMemory(2*bufferSize+1))
+		assert.Equal(t, bufferSize, mr.cfg.chunkSize)
+		assert.Equal(t, 2, len(mr.ring))
+		mr = newMulteeReader(strings.NewReader("foo"), WithMaxMemory(1024))
+		assert.Equal(t, 1024, mr.cfg.chunkSize)
+		assert.Equal(t, 1, len(mr.ring))
+	})
+	t.Run("With_hooks", func(t *testing.T) {
+		var loaded []int
+		var joined int
+		var closed []error
+		mr := NewMulteeReader(iotest.OneByteReader(strings.NewReader("foo")), WithHooks(Hooks{
+			OnChunkLoad:   func(n int, err error) { loaded = append(loaded, n) },
+			OnReaderJoin:  func() { joined++ },
+			OnReaderClose: func(err error) { closed = append(closed, err) },
+		}))
+		r := mr.NewReader()
+		b, err := io.ReadAll(r)
+		assert.Equal(t, []byte("foo"), b)
+		assert.Nil(t, err)
+		assert.NoError(t, r.Close())
+		assert.Equal(t, []int{1, 1, 1, 0}, loaded)
+		assert.Equal(t, 1, joined)
+		assert.Equal(t, []error{ErrClosed}, closed)
+	})
+}
+
+func TestNewMulteeReaderContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	mr := NewMulteeReaderContext(ctx, iotest.OneByteReader(strings.NewReader("foo")))
+	r1 := mr.NewReader()
+	r2 := mr.NewReader()
+	defer r2.Close()
+	p := make([]byte, 4)
+	bytesRead, err := r1.Read(p)
+	assert.Equal(t, 1, bytesRead)
+	assert.Equal(t, []byte("f"), p[0:bytesRead])
+	assert.Nil(t, err)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		// This waits for r2, until the context is cancelled.
+		bytesRead, err := r1.Read(p)
+		assert.Equal(t, 0, bytesRead)
+		assert.ErrorIs(t, err, context.Canceled)
+	}()
+	cancel()
+	<-done
+	bytesRead, err = r2.Read(p)
+	assert.Equal(t, 0, bytesRead)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.NoError(t, r1.Close())
 }
 
 func Test_multeeReader_NewReader(t *testing.T) {
-	mr := NewMulteeReader(strings.NewReader("foo"))
-	r := mr.NewReader()
+	mr := newMulteeReader(strings.NewReader("foo"))
+	r := mr.newReader(JoinNextChunk)
 	assert.False(t, r.closed)
 	assert.Equal(t, 0, r.bufOffset)
 }
 
 func Test_multeeReader_read_impossible_offset(t *testing.T) {
-	mr := NewMulteeReader(strings.NewReader("foo"))
+	mr := newMulteeReader(strings.NewReader("foo"))
+	r := mr.newReader(JoinCurrentChunk)
+	defer r.Close()
+	other := mr.newReader(JoinCurrentChunk)
+	defer other.Close()
+	r.bufOffset = 4
+	bytesRead, err := mr.read(r, make([]byte, 3))
+	assert.Equal(t, 0, bytesRead)
+	assert.ErrorIs(t, err, ErrInternalState)
+	var stateErr *InternalStateError
+	if assert.ErrorAs(t, err, &stateErr) {
+		assert.Equal(t, InternalStateError{Chunk: 1, BufOffset: 4, EndPos: 3}, *stateErr)
+	}
+	// The multeeReader is poisoned, so all other readers get the same error.
+	bytesRead, err = other.Read(make([]byte, 3))
+	assert.Equal(t, 0, bytesRead)
+	assert.Same(t, stateErr, err)
+}
+
+// Returns all its data together with err in a single read.
+type dataErrReader struct {
+	data []byte
+	err  error
+}
+
+func (r *dataErrReader) Read(p []byte) (int, error) {
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	if len(r.data) > 0 {
+		return n, nil
+	}
+	return n, r.err
+}
+
+func Test_multeeReader_read_source_error(t *testing.T) {
+	errFoo := errors.New("foo")
+	mr := NewMulteeReader(&dataErrReader{data: []byte("foo"), err: errFoo})
+	r1 := mr.NewReader()
+	defer r1.Close()
+	r2 := mr.NewReader()
+	defer r2.Close()
+	p := make([]byte, 2)
+	bytesRead, err := r1.Read(p)
+	assert.Equal(t, 2, bytesRead)
+	assert.Nil(t, err)
+	bytesRead, err = r1.Read(p)
+	assert.Equal(t, 1, bytesRead)
+	assert.ErrorIs(t, err, errFoo)
+	assert.ErrorIs(t, err, ErrSource)
+	bytesRead, err = r1.Read(p)
+	assert.Equal(t, 0, bytesRead)
+	assert.ErrorIs(t, err, errFoo)
+	// r2 hasn't read anything yet, so it gets all bytes before the error first.
+	b, err := io.ReadAll(r2)
+	assert.Equal(t, []byte("foo"), b)
+	assert.ErrorIs(t, err, errFoo)
+	// Readers that join after the error get it right away.
+	r3 := mr.NewReader()
+	defer r3.Close()
+	bytesRead, err = r3.Read(p)
+	assert.Equal(t, 0, bytesRead)
+	assert.ErrorIs(t, err, errFoo)
+}
+
+// Panics on its first read, and reads from the wrapped reader after that.
+type panickingReader struct {
+	io.Reader
+	panicked bool
+}
+
+func (r *panickingReader) Read(p []byte) (int, error) {
+	if !r.panicked {
+		r.panicked = true
+		panic("foo")
+	}
+	return r.Reader.Read(p)
+}
+
+func Test_multeeReader_read_source_panic(t *testing.T) {
+	mr := NewMulteeReader(&panickingReader{Reader: strings.NewReader("foo")})
 	r := mr.NewReader()
 	defer r.Close()
-	assert.Panics(t, func() {
-		_, _, _ = mr.read(make([]byte, 3), 1)
+	assert.PanicsWithValue(t, "foo", func() {
+		r.Read(make([]byte, 3))
+	})
+	// The multeeReader is still usable, the next read loads the chunk again.
+	b, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", string(b))
+}
+
+func Test_multeeReader_NewReaderJoining(t *testing.T) {
+	t.Run("Join_current_chunk", func(t *testing.T) {
+		mr := NewMulteeReader(strings.NewReader("foobar"))
+		r1 := mr.NewReader()
+		p := make([]byte, 3)
+		bytesRead, err := r1.Read(p)
+		assert.Equal(t, 3, bytesRead)
+		assert.Equal(t, []byte("foo"), p[0:bytesRead])
+		assert.Nil(t, err)
+		r2 := mr.NewReaderJoining(JoinCurrentChunk)
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			b, err := io.ReadAll(r1)
+			assert.Equal(t, []byte("bar"), b)
+			assert.Nil(t, err)
+		}()
+		go func() {
+			defer wg.Done()
+			b, err := io.ReadAll(r2)
+			assert.Equal(t, []byte("foobar"), b)
+			assert.Nil(t, err)
+		}()
+		wg.Wait()
+	})
+	t.Run("Join_next_chunk", func(t *testing.T) {
+		mr := NewMulteeReader(iotest.OneByteReader(strings.NewReader("foobar")))
+		r1 := mr.NewReader()
+		p := make([]byte, 1)
+		bytesRead, err := r1.Read(p)
+		assert.Equal(t, 1, bytesRead)
+		assert.Equal(t, []byte("f"), p[0:bytesRead])
+		assert.Nil(t, err)
+		r2 := mr.NewReaderJoining(JoinNextChunk)
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			b, err := io.ReadAll(r1)
+			assert.Equal(t, []byte("oobar"), b)
+			assert.Nil(t, err)
+		}()
+		go func() {
+			defer wg.Done()
+			b, err := io.ReadAll(r2)
+			assert.Equal(t, []byte("oobar"), b)
+			assert.Nil(t, err)
+		}()
+		wg.Wait()
+	})
+	t.Run("Join_while_closing", func(t *testing.T) {
+		mr := NewMulteeReader(strings.NewReader("foo"))
+		r1 := mr.NewReader()
+		r2 := mr.NewReaderJoining(JoinCurrentChunk)
+		assert.NoError(t, r2.Close())
+		r3 := mr.NewReaderJoining(JoinCurrentChunk)
+		assert.NoError(t, r1.Close())
+		b, err := io.ReadAll(r3)
+		assert.Equal(t, []byte("foo"), b)
+		assert.Nil(t, err)
+	})
+}
+
+func Test_multeeReader_NewReaderFrom(t *testing.T) {
+	t.Run("Retained", func(t *testing.T) {
+		mr := NewMulteeReader(iotest.OneByteReader(strings.NewReader("foobar")), WithChunkSize(1), WithRetention(2))
+		r1 := mr.NewReader()
+		defer r1.Close()
+		p := make([]byte, 4)
+		bytesRead, err := io.ReadFull(r1, p)
+		assert.Equal(t, 4, bytesRead)
+		assert.Nil(t, err)
+		// The input reader has read "foob", of which "oob" is retained, including the extra chunk.
+		_, err = mr.NewReaderFrom(0)
+		assert.ErrorIs(t, err, ErrOffsetEvicted)
+		_, err = mr.NewReaderFrom(5)
+		assert.Error(t, err)
+		r2, err := mr.NewReaderFrom(1)
+		if assert.NoError(t, err) {
+			defer r2.Close()
+			var wg sync.WaitGroup
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				b, err := io.ReadAll(r2)
+				assert.Equal(t, []byte("oobar"), b)
+				assert.Nil(t, err)
+			}()
+			b, err := io.ReadAll(r1)
+			assert.Equal(t, []byte("ar"), b)
+			assert.Nil(t, err)
+			wg.Wait()
+		}
 	})
+	t.Run("Current_offset", func(t *testing.T) {
+		mr := NewMulteeReader(strings.NewReader("foo"))
+		r, err := mr.NewReaderFrom(0)
+		if assert.NoError(t, err) {
+			defer r.Close()
+			b, err := io.ReadAll(r)
+			assert.Equal(t, []byte("foo"), b)
+			assert.Nil(t, err)
+		}
+	})
+}
+
+func Test_multeeReader_NewReaderContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	mr := NewMulteeReader(iotest.OneByteReader(strings.NewReader("foo")))
+	r1 := mr.NewReader()
+	defer r1.Close()
+	r2 := mr.NewReaderContext(ctx)
+	p := make([]byte, 4)
+	bytesRead, err := r1.Read(p)
+	assert.Equal(t, 1, bytesRead)
+	assert.Equal(t, []byte("f"), p[0:bytesRead])
+	assert.Nil(t, err)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		// This waits for r2, until it's detached.
+		b, err := io.ReadAll(r1)
+		assert.Equal(t, []byte("oo"), b)
+		assert.Nil(t, err)
+	}()
+	cancel()
+	<-done
+	bytesRead, err = r2.Read(p)
+	assert.Equal(t, 0, bytesRead)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.NoError(t, r2.Close())
 }
 
 func Test_reader_Read(t *testing.T) {
+	t.Run("Two_readers_with_lookahead", func(t *testing.T) {
+		ir := iotest.OneByteReader(strings.NewReader("foobar"))
+		mr := NewMulteeReader(ir, WithLookahead(3*bufferSize))
+		r1 := mr.NewReader()
+		r2 := mr.NewReader()
+		// r1 can run 3 chunks ahead of r2, without blocking.
+		p := make([]byte, 3)
+		bytesRead, err := io.ReadFull(r1, p)
+		assert.Equal(t, 3, bytesRead)
+		assert.Equal(t, []byte("foo"), p[0:bytesRead])
+		assert.Nil(t, err)
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			b, err := io.ReadAll(r1)
+			assert.Equal(t, []byte("bar"), b)
+			assert.Nil(t, err)
+		}()
+		go func() {
+			defer wg.Done()
+			b, err := io.ReadAll(r2)
+			assert.Equal(t, []byte("foobar"), b)
+			assert.Nil(t, err)
+		}()
+		wg.Wait()
+	})
 	t.Run("Single_reader_empty_input", func(t *testing.T) {
 		ir := strings.NewReader("")
 		mr := NewMulteeReader(ir)
@@ -153,6 +466,129 @@ func Test_reader_Read(t *testing.T) {
 	})
 }
 
+type panickingWriter struct{}
+
+func (panickingWriter) Write(p []byte) (int, error) {
+	panic("foo")
+}
+
+// Only writes the first byte of each write.
+type oneByteWriter struct {
+	strings.Builder
+}
+
+func (w *oneByteWriter) Write(p []byte) (int, error) {
+	return w.Builder.Write(p[:1])
+}
+
+func Test_reader_WriteTo(t *testing.T) {
+	t.Run("Three_readers", func(t *testing.T) {
+		input := strings.Repeat("foobar", 10000)
+		mr := NewMulteeReader(strings.NewReader(input), WithChunkSize(1000), WithLookahead(3000))
+		var wg sync.WaitGroup
+		for i := 0; i < 3; i++ {
+			r := mr.NewReader()
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer r.Close()
+				var sb strings.Builder
+				written, err := io.Copy(&sb, r)
+				assert.Equal(t, int64(len(input)), written)
+				assert.Equal(t, input, sb.String())
+				assert.Nil(t, err)
+			}()
+		}
+		wg.Wait()
+	})
+	t.Run("Panicking_writer", func(t *testing.T) {
+		mr := NewMulteeReader(iotest.OneByteReader(strings.NewReader("foobar")), WithChunkSize(1))
+		r1 := mr.NewReader()
+		r2 := mr.NewReader()
+		defer r2.Close()
+		assert.PanicsWithValue(t, "foo", func() {
+			r1.(io.WriterTo).WriteTo(panickingWriter{})
+		})
+		assert.NoError(t, r1.Close())
+		// The chunk r1 was writing has been unpinned, so its slot can be reused.
+		b, err := io.ReadAll(r2)
+		assert.Equal(t, "foobar", string(b))
+		assert.NoError(t, err)
+	})
+	t.Run("Spilled", func(t *testing.T) {
+		mr := NewMulteeReader(iotest.OneByteReader(strings.NewReader("foobar")), WithSpillToDisk(t.TempDir(), 0))
+		r1 := mr.NewReader()
+		defer r1.Close()
+		r2 := mr.NewReader()
+		defer r2.Close()
+		var sb strings.Builder
+		_, err := io.Copy(&sb, r1)
+		assert.Equal(t, "foobar", sb.String())
+		assert.Nil(t, err)
+		sb.Reset()
+		_, err = io.Copy(&sb, r2)
+		assert.Equal(t, "foobar", sb.String())
+		assert.Nil(t, err)
+	})
+	t.Run("Spilled_short_write", func(t *testing.T) {
+		mr := NewMulteeReader(strings.NewReader("foobar"), WithChunkSize(3), WithSpillToDisk(t.TempDir(), 0))
+		r1 := mr.NewReader()
+		defer r1.Close()
+		r2 := mr.NewReader()
+		defer r2.Close()
+		_, err := io.ReadAll(r1)
+		assert.Nil(t, err)
+		// The first chunk has been spilled to disk, but only its first byte is written.
+		var w oneByteWriter
+		written, err := r2.(io.WriterTo).WriteTo(&w)
+		assert.Equal(t, int64(1), written)
+		assert.Equal(t, io.ErrShortWrite, err)
+		assert.Equal(t, "f", w.String())
+		assert.Equal(t, int64(1), r2.Offset())
+		b, err := io.ReadAll(r2)
+		assert.Equal(t, "oobar", string(b))
+		assert.Nil(t, err)
+	})
+	t.Run("Source_error", func(t *testing.T) {
+		errFoo := errors.New("foo")
+		mr := NewMulteeReader(&dataErrReader{data: []byte("foo"), err: errFoo})
+		r := mr.NewReader()
+		defer r.Close()
+		var sb strings.Builder
+		written, err := io.Copy(&sb, r)
+		assert.Equal(t, int64(3), written)
+		assert.Equal(t, "foo", sb.String())
+		assert.ErrorIs(t, err, errFoo)
+	})
+}
+
+func Test_reader_Offset(t *testing.T) {
+	mr := NewMulteeReader(strings.NewReader("foobar"), WithChunkSize(4), WithLookahead(8))
+	r1 := mr.NewReader()
+	defer r1.Close()
+	r2 := mr.NewReader()
+	defer r2.Close()
+	assert.Equal(t, int64(0), mr.SourceOffset())
+	p := make([]byte, 3)
+	_, err := io.ReadFull(r1, p)
+	assert.Nil(t, err)
+	_, err = io.ReadFull(r1, p)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(6), mr.SourceOffset())
+	assert.Equal(t, int64(6), r1.Offset())
+	assert.Equal(t, int64(0), r1.Lag())
+	_, err = io.ReadFull(r2, p[:1])
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), r2.Offset())
+	assert.Equal(t, int64(5), r2.Lag())
+	r3, err := mr.NewReaderFrom(2)
+	if assert.NoError(t, err) {
+		defer r3.Close()
+		assert.Equal(t, int64(2), r3.Offset())
+		assert.Equal(t, int64(4), r3.Lag())
+	}
+}
+
 func Test_reader_Close(t *testing.T) {
 	t.Run("Closing_twice", func(t *testing.T) {
 		mr := NewMulteeReader(strings.NewReader("foo"))
@@ -164,3 +600,42 @@ func Test_reader_Close(t *testing.T) {
 		}
 	})
 }
+
+// This signals on called each time Read is called, before reading from the wrapped reader.
+type signalingReader struct {
+	io.Reader
+	called chan struct{}
+}
+
+func (r signalingReader) Read(p []byte) (int, error) {
+	r.called <- struct{}{}
+	return r.Reader.Read(p)
+}
+
+func Test_reader_concurrency_checks(t *testing.T) {
+	for _, strategy := range []Strategy{StrategyRing, StrategyChannel} {
+		t.Run(fmt.Sprintf("Strategy_%d", strategy), func(t *testing.T) {
+			ir, iw := io.Pipe()
+			called := make(chan struct{}, 1)
+			mr := NewMulteeReader(signalingReader{ir, called}, WithStrategy(strategy), WithConcurrencyChecks())
+			r := mr.NewReader()
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				n, err := r.Read(make([]byte, 1))
+				assert.Equal(t, 1, n)
+				assert.NoError(t, err)
+			}()
+			// Once the input reader is being read, the first Read is in progress.
+			<-called
+			_, err := r.Read(make([]byte, 1))
+			assert.Equal(t, ErrConcurrentUse, err)
+			assert.Equal(t, ErrConcurrentUse, r.Close())
+			_, err = iw.Write([]byte("f"))
+			assert.NoError(t, err)
+			<-done
+			assert.NoError(t, r.Close())
+			iw.Close()
+		})
+	}
+}
@@ -0,0 +1,309 @@
+// Copyright 2023-2025 Roel Harbers.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+// Package multeetest implements a conformance test suite, that all implementations of multee.MulteeReader must pass.
+package multeetest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ComaVN/multee"
+)
+
+// Factory returns a new MulteeReader for inputReader, implemented by the implementation under test.
+type Factory func(inputReader io.Reader) multee.MulteeReader
+
+// Timeout is the maximum time each test may take, before it's considered deadlocked.
+var Timeout = 10 * time.Second
+
+// Read sizes used by the readers in turn, including sizes that don't align with any buffer size.
+var readSizes = []int{1, 7, 4096, 65537}
+
+// Run runs the conformance test suite against the MulteeReaders returned by newMulteeReader.
+func Run(t *testing.T, newMulteeReader Factory) {
+	t.Run("Equal_bytes", func(t *testing.T) {
+		testEqualBytes(t, newMulteeReader)
+	})
+	t.Run("Source_error", func(t *testing.T) {
+		testSourceError(t, newMulteeReader)
+	})
+	t.Run("Close", func(t *testing.T) {
+		testClose(t, newMulteeReader)
+	})
+	t.Run("Early_close", func(t *testing.T) {
+		testEarlyClose(t, newMulteeReader)
+	})
+	t.Run("Stats", func(t *testing.T) {
+		testStats(t, newMulteeReader)
+	})
+	t.Run("Short_read", func(t *testing.T) {
+		testShortRead(t, newMulteeReader)
+	})
+	t.Run("Concurrent_offset", func(t *testing.T) {
+		testConcurrentOffset(t, newMulteeReader)
+	})
+}
+
+// Returns n predictable bytes.
+func input(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(i * 7 % 251)
+	}
+	return b
+}
+
+// Runs f for each of n readers in its own goroutine, and waits for them, failing the test if that takes too long.
+func runReaders(t *testing.T, readers []multee.Reader, f func(idx int, r multee.Reader)) {
+	t.Helper()
+	var wg sync.WaitGroup
+	wg.Add(len(readers))
+	for idx, r := range readers {
+		go func(idx int, r multee.Reader) {
+			defer wg.Done()
+			f(idx, r)
+		}(idx, r)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		wg.Wait()
+	}()
+	select {
+	case <-done:
+	case <-time.After(Timeout):
+		t.Fatalf("readers didn't finish within %v", Timeout)
+	}
+}
+
+// Reads from r until an error, using the given read size, with a zero-length read before each read.
+// Returns all bytes read, and the error.
+func readAll(t *testing.T, r io.Reader, readSize int) ([]byte, error) {
+	b := []byte{}
+	p := make([]byte, readSize)
+	for {
+		n, err := r.Read(p[:0])
+		if !assert.Equal(t, 0, n, "zero-length read") {
+			return b, err
+		}
+		if err != nil {
+			return b, err
+		}
+		n, err = r.Read(p)
+		b = append(b, p[:n]...)
+		if err != nil {
+			return b, err
+		}
+	}
+}
+
+func testEqualBytes(t *testing.T, newMulteeReader Factory) {
+	sources := []struct {
+		name string
+		f    func(io.Reader) io.Reader
+	}{
+		{"Plain", func(r io.Reader) io.Reader { return r }},
+		{"One_byte", iotest.OneByteReader},
+		{"Data_err", iotest.DataErrReader},
+		{"Half", iotest.HalfReader},
+	}
+	for _, src := range sources {
+		for _, inputLen := range []int{0, 1, 3, 100000} {
+			if src.name == "One_byte" && inputLen > 1000 {
+				// This would take too long.
+				inputLen = 1000
+			}
+			for _, numReaders := range []int{1, 3} {
+				src, inputLen, numReaders := src, inputLen, numReaders
+				t.Run(fmt.Sprintf("%s_source_%d_bytes_%d_readers", src.name, inputLen, numReaders), func(t *testing.T) {
+					in := input(inputLen)
+					mr := newMulteeReader(src.f(bytes.NewReader(in)))
+					readers := make([]multee.Reader, numReaders)
+					for idx := range readers {
+						readers[idx] = mr.NewReader()
+					}
+					runReaders(t, readers, func(idx int, r multee.Reader) {
+						defer r.Close()
+						b, err := readAll(t, r, readSizes[idx%len(readSizes)])
+						assert.Equal(t, in, b, "reader %d", idx)
+						assert.Equal(t, io.EOF, err, "reader %d", idx)
+					})
+				})
+			}
+		}
+	}
+}
+
+func testSourceError(t *testing.T, newMulteeReader Factory) {
+	errFoo := errors.New("foo")
+	sources := []struct {
+		name    string
+		f       func(io.Reader) io.Reader
+		wantErr error
+		partial bool // The error is returned before the whole input has been read.
+	}{
+		{"Err", func(r io.Reader) io.Reader { return io.MultiReader(r, iotest.ErrReader(errFoo)) }, errFoo, false},
+		{"Timeout", iotest.TimeoutReader, iotest.ErrTimeout, true},
+		{"Unexpected_EOF", func(r io.Reader) io.Reader { return io.MultiReader(r, iotest.ErrReader(io.ErrUnexpectedEOF)) }, io.ErrUnexpectedEOF, false},
+	}
+	for _, src := range sources {
+		src := src
+		t.Run(src.name, func(t *testing.T) {
+			in := input(10000)
+			mr := newMulteeReader(src.f(bytes.NewReader(in)))
+			readers := make([]multee.Reader, 3)
+			for idx := range readers {
+				readers[idx] = mr.NewReader()
+			}
+			got := make([][]byte, len(readers))
+			runReaders(t, readers, func(idx int, r multee.Reader) {
+				defer r.Close()
+				// All bytes read before the error are returned before it.
+				b, err := readAll(t, r, readSizes[idx%len(readSizes)])
+				got[idx] = b
+				if src.partial {
+					assert.True(t, len(b) > 0 && bytes.HasPrefix(in, b), "reader %d: only part of the input expected", idx)
+				} else {
+					assert.Equal(t, in, b, "reader %d", idx)
+				}
+				assert.ErrorIs(t, err, src.wantErr, "reader %d", idx)
+				// The error is final.
+				_, err = r.Read(make([]byte, 1))
+				assert.ErrorIs(t, err, src.wantErr, "reader %d", idx)
+			})
+			for idx := range got {
+				assert.Equal(t, got[0], got[idx], "reader %d", idx)
+			}
+		})
+	}
+}
+
+func testClose(t *testing.T, newMulteeReader Factory) {
+	mr := newMulteeReader(bytes.NewReader(input(3)))
+	r := mr.NewReader()
+	assert.NoError(t, r.Close())
+	assert.ErrorIs(t, r.Close(), multee.ErrClosed)
+	_, err := r.Read(make([]byte, 1))
+	assert.ErrorIs(t, err, multee.ErrClosed)
+	// Also after reading until EOF.
+	r = mr.NewReader()
+	_, err = io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.NoError(t, r.Close())
+	_, err = r.Read(make([]byte, 1))
+	assert.ErrorIs(t, err, multee.ErrClosed)
+}
+
+func testEarlyClose(t *testing.T, newMulteeReader Factory) {
+	in := input(100000)
+	mr := newMulteeReader(bytes.NewReader(in))
+	readers := make([]multee.Reader, 3)
+	for idx := range readers {
+		readers[idx] = mr.NewReader()
+	}
+	runReaders(t, readers, func(idx int, r multee.Reader) {
+		switch idx {
+		case 0:
+			// Closes without reading anything.
+			assert.NoError(t, r.Close())
+		case 1:
+			// Closes after reading part of the input.
+			_, err := io.ReadFull(r, make([]byte, 100))
+			assert.NoError(t, err)
+			assert.NoError(t, r.Close())
+		default:
+			defer r.Close()
+			b, err := io.ReadAll(r)
+			assert.Equal(t, in, b)
+			assert.NoError(t, err)
+		}
+	})
+}
+
+// All calls to Read are counted, including zero-length reads, and reads that only return an error.
+func testStats(t *testing.T, newMulteeReader Factory) {
+	in := input(10)
+	mr := newMulteeReader(iotest.OneByteReader(bytes.NewReader(in)))
+	r := mr.NewReader()
+	defer r.Close()
+	var reads int64
+	p := make([]byte, 3)
+	for {
+		_, err := r.Read(p[:0])
+		reads++
+		if err == nil {
+			_, err = r.Read(p)
+			reads++
+		}
+		if err != nil {
+			assert.Equal(t, io.EOF, err)
+			break
+		}
+	}
+	stats := r.Stats()
+	assert.Equal(t, int64(len(in)), stats.Bytes)
+	assert.Equal(t, reads, stats.Reads)
+}
+
+func testShortRead(t *testing.T, newMulteeReader Factory) {
+	in := input(3)
+	pr, pw := io.Pipe()
+	mr := newMulteeReader(pr)
+	r := mr.NewReader()
+	defer r.Close()
+	defer pw.Close()
+	go pw.Write(in)
+	// Like any io.Reader, a read returns the bytes that are available, rather than waiting for p to be filled.
+	runReaders(t, []multee.Reader{r}, func(idx int, r multee.Reader) {
+		p := make([]byte, 100)
+		n, err := r.Read(p)
+		assert.Equal(t, in, p[:n])
+		assert.NoError(t, err)
+	})
+}
+
+// Offset, Lag and Stats are concurrency-safe, run this with -race to check that.
+func testConcurrentOffset(t *testing.T, newMulteeReader Factory) {
+	in := input(100000)
+	mr := newMulteeReader(bytes.NewReader(in))
+	r := mr.NewReader()
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		var prev int64
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			offset := r.Offset()
+			assert.GreaterOrEqual(t, offset, prev, "offset went back")
+			prev = offset
+			r.Lag()
+			r.Stats()
+			mr.SourceOffset()
+		}
+	}()
+	runReaders(t, []multee.Reader{r}, func(idx int, r multee.Reader) {
+		defer r.Close()
+		b, err := readAll(t, r, readSizes[1])
+		assert.Equal(t, in, b)
+		assert.Equal(t, io.EOF, err)
+	})
+	close(stop)
+	<-stopped
+	assert.Equal(t, int64(len(in)), r.Offset())
+	assert.Equal(t, int64(0), r.Lag())
+}
@@ -0,0 +1,192 @@
+// Copyright 2023-2025 Roel Harbers.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package multee
+
+import "time"
+
+// Option configures a multeeReader, see NewMulteeReader.
+type Option func(*config)
+
+type config struct {
+	strategy         Strategy
+	chunkSize        int
+	lookahead        int
+	retention        int64
+	maxMemory        int
+	slowReaderPolicy SlowReaderPolicy
+	maxStall         time.Duration
+	maxLag           int64
+	observers        observers
+	debug            *Debug
+	checked          bool
+	spill            bool
+	spillDir         string
+	spillSegmentSize int64
+}
+
+func newConfig(opts []Option) config {
+	cfg := config{
+		chunkSize: bufferSize,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.maxMemory > 0 {
+		cfg.chunkSize = min(cfg.chunkSize, cfg.maxMemory)
+	}
+	if cfg.strategy == StrategyBarrier {
+		cfg.lookahead, cfg.retention, cfg.spill = 0, 0, false
+	}
+	return cfg
+}
+
+// Returns the number of chunks in the ring.
+func (cfg config) ringSize() int {
+	n := max(1, (cfg.lookahead+cfg.chunkSize-1)/cfg.chunkSize)
+	if cfg.retention > 0 {
+		// One extra chunk, because the oldest chunk is no longer retained while the next chunk is being loaded.
+		n = max(n, int((cfg.retention+int64(cfg.chunkSize)-1)/int64(cfg.chunkSize))+1)
+	}
+	if cfg.strategy == StrategyRing && cfg.slowReaderPolicy != SlowReaderBlock && cfg.maxLag > 0 {
+		// The ring must hold more than maxLag bytes, so a reader can fall further behind, before the other readers wait for it.
+		n = max(n, int(cfg.maxLag/int64(cfg.chunkSize))+1)
+	}
+	if cfg.maxMemory > 0 {
+		n = max(1, min(n, cfg.maxMemory/cfg.chunkSize))
+	}
+	return n
+}
+
+// Strategy selects how a multeeReader multiplexes its input to its readers, see WithStrategy.
+type Strategy int
+
+const (
+	// StrategyRing buffers the input in a ring of chunks, so fast readers can run ahead of slow readers, see WithLookahead.
+	// It supports all options.
+	StrategyRing Strategy = iota
+	// StrategyBarrier makes all readers read the same chunk in lockstep, and wait for each other at the end of each chunk.
+	// It ignores WithLookahead, WithRetention and WithSpillToDisk.
+	StrategyBarrier
+	// StrategyChannel reads the input in a goroutine, which sends each chunk to a channel per reader.
+	// The channels buffer as many chunks as the lookahead allows, see WithLookahead.
+	// It ignores WithRetention, WithSlowReaderPolicy and WithSpillToDisk.
+	StrategyChannel
+)
+
+// WithStrategy selects how the multeeReader multiplexes its input to its readers.
+// The default is StrategyRing. NewMulteeWriter always uses StrategyRing.
+func WithStrategy(strategy Strategy) Option {
+	return func(cfg *config) {
+		cfg.strategy = strategy
+	}
+}
+
+// WithChunkSize sets the maximum number of bytes read from the input reader at once.
+// The default chunk size is 32 KiB.
+func WithChunkSize(chunkSize int) Option {
+	return func(cfg *config) {
+		if chunkSize > 0 {
+			cfg.chunkSize = chunkSize
+		}
+	}
+}
+
+// WithLookahead sets how many bytes the fastest readers may run ahead of the slowest reader.
+// The bytes are buffered in a ring of chunks, so the actual lookahead is rounded up to a whole number of chunks.
+// The default lookahead is a single chunk, which means all readers read the same chunk in lockstep.
+func WithLookahead(lookahead int) Option {
+	return func(cfg *config) {
+		cfg.lookahead = lookahead
+	}
+}
+
+// WithRetention makes the multeeReader retain at least the last retention bytes read from the input reader,
+// so NewReaderFrom can start new readers at an offset the other readers have already read past.
+// To retain the whole stream, up to a cap, use the cap as retention, since chunks are only allocated when they're needed.
+// The bytes are retained in the ring of chunks, which means fast readers may also run ahead that far, see WithLookahead.
+// Since each chunk holds the bytes of a single read from the input reader, less bytes are retained when these reads are short.
+func WithRetention(retention int64) Option {
+	return func(cfg *config) {
+		cfg.retention = retention
+	}
+}
+
+// WithMaxMemory limits the number of bytes used for buffering input, by limiting the chunk size and the lookahead.
+// At least a single chunk is always used.
+// The default is no limit, besides the chunk size and lookahead.
+func WithMaxMemory(maxMemory int) Option {
+	return func(cfg *config) {
+		cfg.maxMemory = maxMemory
+	}
+}
+
+// WithSlowReaderPolicy sets what happens to readers that hold up the other readers.
+// A reader is too slow when it keeps the other readers waiting for a free chunk in the ring for longer than maxStall,
+// or when it has fallen more than maxLag bytes behind the input reader while the other readers are waiting for it.
+// A zero maxStall or maxLag disables that check.
+// Since a reader can't fall further behind than the ring holds, the ring is made large enough to hold more than maxLag bytes,
+// which means fast readers may also run ahead that far, see WithLookahead. Like with WithRetention,
+// less bytes fit when the reads from the input reader are short, and WithMaxMemory may limit the ring further.
+// StrategyBarrier doesn't grow the ring, so its readers only fall behind by less than a chunk.
+// The default policy is SlowReaderBlock.
+func WithSlowReaderPolicy(policy SlowReaderPolicy, maxStall time.Duration, maxLag int64) Option {
+	return func(cfg *config) {
+		cfg.slowReaderPolicy = policy
+		cfg.maxStall = maxStall
+		cfg.maxLag = maxLag
+	}
+}
+
+// WithSpillToDisk makes the multeeReader write chunks that are still needed by slow readers to temporary files in dir,
+// when the fast readers need their place in the ring, instead of making the fast readers wait.
+// This means the readers never wait for each other, at the cost of disk space.
+// The temporary files are created using os.CreateTemp, so an empty dir means the default directory for temporary files.
+// Each file contains up to segmentSize bytes, and is removed once all readers have read past it.
+// A zero segmentSize means 64 MiB.
+// Note that files of readers that are never closed, or read to EOF, are never removed.
+func WithSpillToDisk(dir string, segmentSize int64) Option {
+	return func(cfg *config) {
+		cfg.spill = true
+		cfg.spillDir = dir
+		cfg.spillSegmentSize = segmentSize
+		if segmentSize <= 0 {
+			cfg.spillSegmentSize = defaultSegmentSize
+		}
+	}
+}
+
+// WithHooks adds functions to be called on events in the multeeReader, see Hooks.
+func WithHooks(hooks Hooks) Option {
+	return WithObserver(hooksObserver{hooks})
+}
+
+// WithObserver adds an Observer, that gets called on events in the multeeReader.
+// Observers are called in the order they were added.
+func WithObserver(observer Observer) Option {
+	return func(cfg *config) {
+		cfg.observers = append(cfg.observers, observer)
+	}
+}
+
+// WithDebug enables the debug mode, which reports readers that were garbage collected without being closed,
+// and readers that keep the other readers waiting for too long, see Debug.
+// Readers that were garbage collected without being closed are closed automatically, so they no longer block the other readers.
+// This is not for production use, since it records a stack trace for each new reader.
+// It only applies to StrategyRing and StrategyBarrier.
+func WithDebug(debug Debug) Option {
+	return func(cfg *config) {
+		cfg.debug = &debug
+	}
+}
+
+// WithConcurrencyChecks makes readers detect when they are used by several goroutines at once.
+// Since readers are not concurrency-safe, a Read, WriteTo or Close that overlaps with another call on the same reader
+// returns ErrConcurrentUse, without affecting the state of the reader or the multeeReader.
+// Calls that do not overlap, but are not synchronized either, are not detected, use the race detector for that.
+func WithConcurrencyChecks() Option {
+	return func(cfg *config) {
+		cfg.checked = true
+	}
+}
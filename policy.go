@@ -0,0 +1,89 @@
+// Copyright 2023-2025 Roel Harbers.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package multee
+
+import "time"
+
+// SlowReaderPolicy determines what happens to readers that are too slow, see WithSlowReaderPolicy.
+type SlowReaderPolicy int
+
+const (
+	// SlowReaderBlock makes the other readers wait for slow readers, however slow they are.
+	SlowReaderBlock SlowReaderPolicy = iota
+	// SlowReaderDetach detaches readers that are too slow, as if they were closed.
+	// Their next Read returns ErrReaderTooSlow, and they must still be closed.
+	SlowReaderDetach
+	// SlowReaderFail fails the whole multeeReader when any reader is too slow.
+	// All reads from all readers return ErrReaderTooSlow.
+	SlowReaderFail
+)
+
+// Applies the slow reader policy to the readers that are keeping other readers waiting.
+// Returns whether any reader was too slow.
+// Must be called with mu locked, when a reader is about to wait for a slot in the ring to be freed.
+func (mr *multeeReader) checkSlowReaders() bool {
+	mr.startWatchdog()
+	if mr.cfg.slowReaderPolicy == SlowReaderBlock {
+		return false
+	}
+	found := false
+	if mr.cfg.maxLag > 0 {
+		for r := range mr.readers {
+			if mr.blocking(r) && mr.lag(r) > mr.cfg.maxLag {
+				mr.tooSlow(r)
+				found = true
+			}
+		}
+	}
+	if mr.cfg.maxStall > 0 && mr.stallTimer == nil {
+		head := mr.head
+		var timer *time.Timer
+		timer = time.AfterFunc(mr.cfg.maxStall, func() {
+			mr.mu.Lock()
+			defer mr.mu.Unlock()
+			if mr.head != head || mr.loading {
+				// The stall is already over.
+				return
+			}
+			if mr.slotWaiters == 0 {
+				// The readers that were waiting have gone, so the slow readers don't hold anyone up anymore.
+				if mr.stallTimer == timer {
+					mr.stallTimer = nil
+				}
+				return
+			}
+			for r := range mr.readers {
+				if mr.blocking(r) {
+					mr.tooSlow(r)
+				}
+			}
+		})
+		mr.stallTimer = timer
+	}
+	return found
+}
+
+// Must be called with mu locked.
+func (mr *multeeReader) tooSlow(r *reader) {
+	switch mr.cfg.slowReaderPolicy {
+	case SlowReaderDetach:
+		mr.detach(r, ErrReaderTooSlow)
+	case SlowReaderFail:
+		mr.abort(ErrReaderTooSlow)
+	}
+}
+
+// Stops the stall timer, and the watchdog timer of the debug mode.
+// Must be called with mu locked.
+func (mr *multeeReader) stopStallTimer() {
+	if mr.stallTimer != nil {
+		mr.stallTimer.Stop()
+		mr.stallTimer = nil
+	}
+	if mr.watchdogTimer != nil {
+		mr.watchdogTimer.Stop()
+		mr.watchdogTimer = nil
+	}
+}
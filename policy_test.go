@@ -0,0 +1,135 @@
+// Copyright 2023-2025 Roel Harbers.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package multee
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithSlowReaderPolicy(t *testing.T) {
+	t.Run("Detach_after_max_lag", func(t *testing.T) {
+		mr := NewMulteeReader(strings.NewReader("foobar"), WithSlowReaderPolicy(SlowReaderDetach, 0, 3))
+		r1 := mr.NewReader()
+		defer r1.Close()
+		r2 := mr.NewReader()
+		defer r2.Close()
+		p := make([]byte, 6)
+		bytesRead, err := r1.Read(p)
+		assert.Equal(t, 6, bytesRead)
+		assert.Nil(t, err)
+		bytesRead, err = r2.Read(p[:1])
+		assert.Equal(t, 1, bytesRead)
+		assert.Nil(t, err)
+		// r2 lags 5 bytes behind, so it's detached as soon as r1 has to wait for it.
+		bytesRead, err = r1.Read(p)
+		assert.Equal(t, 0, bytesRead)
+		assert.Equal(t, io.EOF, err)
+		bytesRead, err = r2.Read(p)
+		assert.Equal(t, 0, bytesRead)
+		assert.ErrorIs(t, err, ErrReaderTooSlow)
+	})
+	t.Run("Detach_after_max_lag_beyond_lookahead", func(t *testing.T) {
+		// The ring holds 6 bytes, so r2 can fall more than 5 bytes behind, before r1 has to wait for it.
+		mr := NewMulteeReader(strings.NewReader("foobarbazqux"), WithChunkSize(2), WithSlowReaderPolicy(SlowReaderDetach, 0, 5))
+		r1 := mr.NewReader()
+		defer r1.Close()
+		r2 := mr.NewReader()
+		defer r2.Close()
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			b, err := io.ReadAll(r1)
+			assert.Equal(t, []byte("foobarbazqux"), b)
+			assert.Nil(t, err)
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("r1 is still waiting for r2")
+		}
+		bytesRead, err := r2.Read(make([]byte, 1))
+		assert.Equal(t, 0, bytesRead)
+		assert.ErrorIs(t, err, ErrReaderTooSlow)
+	})
+	t.Run("Detach_after_max_stall", func(t *testing.T) {
+		mr := NewMulteeReader(strings.NewReader("foobar"), WithSlowReaderPolicy(SlowReaderDetach, 10*time.Millisecond, 0))
+		r1 := mr.NewReader()
+		defer r1.Close()
+		r2 := mr.NewReaderJoining(JoinCurrentChunk)
+		defer r2.Close()
+		b, err := io.ReadAll(r1)
+		assert.Equal(t, []byte("foobar"), b)
+		assert.Nil(t, err)
+		bytesRead, err := r2.Read(make([]byte, 6))
+		assert.Equal(t, 0, bytesRead)
+		assert.ErrorIs(t, err, ErrReaderTooSlow)
+	})
+	t.Run("Fail_after_max_lag", func(t *testing.T) {
+		mr := NewMulteeReader(strings.NewReader("foobar"), WithSlowReaderPolicy(SlowReaderFail, 0, 3))
+		r1 := mr.NewReader()
+		defer r1.Close()
+		r2 := mr.NewReader()
+		defer r2.Close()
+		p := make([]byte, 6)
+		bytesRead, err := r1.Read(p)
+		assert.Equal(t, 6, bytesRead)
+		assert.Nil(t, err)
+		bytesRead, err = r1.Read(p)
+		assert.Equal(t, 0, bytesRead)
+		assert.ErrorIs(t, err, ErrReaderTooSlow)
+		bytesRead, err = r2.Read(p)
+		assert.Equal(t, 0, bytesRead)
+		assert.ErrorIs(t, err, ErrReaderTooSlow)
+	})
+	t.Run("Waiting_reader_canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		mr := NewMulteeReader(strings.NewReader("foobar"), WithSlowReaderPolicy(SlowReaderFail, 10*time.Millisecond, 0))
+		r1 := mr.NewReaderContext(ctx)
+		defer r1.Close()
+		r2 := mr.NewReader()
+		defer r2.Close()
+		_, err := io.ReadFull(r1, make([]byte, 6))
+		assert.NoError(t, err)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			// This waits for r2, until r1 is canceled.
+			_, err := r1.Read(make([]byte, 6))
+			assert.ErrorIs(t, err, context.Canceled)
+		}()
+		time.Sleep(2 * time.Millisecond)
+		cancel()
+		<-done
+		// Nobody is waiting for r2 anymore, so it's not too slow, however long it takes.
+		time.Sleep(20 * time.Millisecond)
+		b, err := io.ReadAll(r2)
+		assert.Equal(t, []byte("foobar"), b)
+		assert.Nil(t, err)
+	})
+	t.Run("Block", func(t *testing.T) {
+		mr := NewMulteeReader(strings.NewReader("foobar"), WithSlowReaderPolicy(SlowReaderBlock, 10*time.Millisecond, 3))
+		r1 := mr.NewReader()
+		r2 := mr.NewReader()
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			b, err := io.ReadAll(r1)
+			assert.Equal(t, []byte("foobar"), b)
+			assert.Nil(t, err)
+		}()
+		time.Sleep(20 * time.Millisecond)
+		b, err := io.ReadAll(r2)
+		assert.Equal(t, []byte("foobar"), b)
+		assert.Nil(t, err)
+		<-done
+	})
+}
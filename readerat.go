@@ -0,0 +1,265 @@
+// Copyright 2023-2025 Roel Harbers.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package multee
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// This is the MulteeReader returned by NewMulteeReaderAt.
+// Each reader reads directly from the input, at its own offset, so nothing is buffered, and readers never wait for each other.
+type readerAtMulteeReader struct {
+	inputReaderAt io.ReaderAt
+	size          int64 // Size of the input, or -1 if unknown.
+	cfg           config
+	mu            sync.Mutex // This guards all fields below.
+	abortErr      error      // This is set when all readers have been aborted.
+	readers       map[*readerAtReader]struct{}
+	offset        int64 // The highest offset read by any reader.
+	reads         uint64
+}
+
+// Returns a MulteeReader for the first size bytes of inputReaderAt, for example an *os.File or a *bytes.Reader.
+// A negative size means the input is read until inputReaderAt returns io.EOF.
+// Its readers behave like the readers of NewMulteeReader, but each reads from inputReaderAt at its own offset,
+// so the input is not buffered, and the readers never wait for each other.
+// This means they don't need to be read concurrently, and a reader that is never read doesn't block the others.
+// Only the WithHooks, WithObserver and WithConcurrencyChecks options apply.
+func NewMulteeReaderAt(inputReaderAt io.ReaderAt, size int64, opts ...Option) MulteeReader {
+	return newReaderAtMulteeReader(inputReaderAt, size, newConfig(opts))
+}
+
+func newReaderAtMulteeReader(inputReaderAt io.ReaderAt, size int64, cfg config) *readerAtMulteeReader {
+	return &readerAtMulteeReader{
+		inputReaderAt: inputReaderAt,
+		size:          max(size, -1),
+		cfg:           cfg,
+		readers:       make(map[*readerAtReader]struct{}),
+	}
+}
+
+var _ multeeImpl = (*readerAtMulteeReader)(nil)
+
+// Returns a reader that starts at the highest offset read by any reader so far, like a reader of NewMulteeReader
+// starts at the next chunk. Use NewReaderFrom to start at any other offset.
+// The returned reader is *not* concurrency-safe.
+func (mr *readerAtMulteeReader) NewReader() Reader {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	return mr.addReader(mr.offset)
+}
+
+// Like NewReader, since there are no chunks, pos is ignored.
+func (mr *readerAtMulteeReader) NewReaderJoining(pos JoinPosition) Reader {
+	return mr.NewReader()
+}
+
+// Like NewReader, but the reader starts at the given absolute offset in the input.
+// Since the whole input is always available, any offset up to the size of the input is valid.
+func (mr *readerAtMulteeReader) NewReaderFrom(offset int64) (Reader, error) {
+	if offset < 0 || mr.size >= 0 && offset > mr.size {
+		return nil, fmt.Errorf("offset %d is outside the input (%d bytes)", offset, mr.size)
+	}
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	return mr.addReader(offset), nil
+}
+
+// Like NewReader, but when ctx is done, the reader is detached from the multeeReader, as if it was closed,
+// and all current and future reads from it return an error matching both ctx.Err() and ErrCanceled.
+// The reader must still be closed.
+func (mr *readerAtMulteeReader) NewReaderContext(ctx context.Context) Reader {
+	r := mr.NewReader().(*readerAtReader)
+	r.stopCtx = context.AfterFunc(ctx, func() {
+		mr.mu.Lock()
+		defer mr.mu.Unlock()
+		mr.detach(r, canceledError(ctx.Err()))
+	})
+	return r
+}
+
+// Must be called with mu locked.
+func (mr *readerAtMulteeReader) addReader(offset int64) *readerAtReader {
+	r := &readerAtReader{
+		multeeReader: mr,
+		offset:       offset,
+	}
+	r.guard.checked = mr.cfg.checked
+	mr.readers[r] = struct{}{}
+	mr.cfg.observers.readerJoin()
+	return r
+}
+
+// Returns the highest offset read by any reader so far.
+func (mr *readerAtMulteeReader) SourceOffset() int64 {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	return mr.offset
+}
+
+// Returns the current statistics of the multeeReader.
+// Chunks is the number of reads from the input by all readers.
+func (mr *readerAtMulteeReader) Stats() Stats {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	return Stats{
+		SourceBytes: mr.offset,
+		Chunks:      mr.reads,
+		Readers:     len(mr.readers),
+	}
+}
+
+// Makes all current and future reads from all readers return err.
+// Reads that are in progress still return their bytes.
+func (mr *readerAtMulteeReader) fail(err error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	if mr.abortErr != nil {
+		return
+	}
+	mr.abortErr = err
+	mr.cfg.observers.error(err)
+}
+
+// Used internally by reader to read from the input at the reader's offset.
+// Only the bookkeeping is done with mu locked, so readers never wait for each other.
+func (mr *readerAtMulteeReader) read(r *readerAtReader, p []byte) (int, error) {
+	mr.mu.Lock()
+	r.stats.Reads++
+	if err := mr.readErr(r); err != nil {
+		mr.mu.Unlock()
+		return 0, err
+	}
+	offset := r.offset
+	mr.mu.Unlock()
+	if mr.size >= 0 {
+		if offset >= mr.size {
+			return 0, io.EOF
+		}
+		p = p[:min(int64(len(p)), mr.size-offset)]
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	start := time.Now()
+	n, err := mr.inputReaderAt.ReadAt(p, offset)
+	blocked := time.Since(start)
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	r.offset += int64(n)
+	r.stats.Blocked += blocked
+	r.stats.Bytes += int64(n)
+	mr.offset = max(mr.offset, r.offset)
+	mr.reads++
+	mr.cfg.observers.chunkLoad(n, err)
+	if err == io.EOF && n == len(p) {
+		// ReaderAt may return io.EOF along with the last bytes, the next read returns it again.
+		return n, nil
+	}
+	if err == io.EOF && mr.size >= 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	if err = sourceError(err, r.offset); err != nil && err != io.EOF {
+		// Like the errors of an input reader, this error is final.
+		r.err = err
+		mr.cfg.observers.error(err)
+	}
+	return n, err
+}
+
+// Returns the error all reads from r return, if any.
+// Must be called with mu locked.
+func (mr *readerAtMulteeReader) readErr(r *readerAtReader) error {
+	if r.err != nil {
+		return r.err
+	}
+	return mr.abortErr
+}
+
+// Removes a reader from the multeeReader, all reads from the reader will return err from now on.
+// Must be called with mu locked.
+func (mr *readerAtMulteeReader) detach(r *readerAtReader, err error) {
+	if _, ok := mr.readers[r]; !ok {
+		// Already detached.
+		return
+	}
+	if r.err == nil {
+		r.err = err
+	}
+	delete(mr.readers, r)
+	mr.cfg.observers.readerClose(err)
+}
+
+// This is the io.ReadCloser returned by readerAtMulteeReader.NewReader.
+type readerAtReader struct {
+	multeeReader *readerAtMulteeReader
+	offset       int64 // Absolute offset in the input of the next byte this reader reads.
+	stats        ReaderStats
+	err          error       // This is set when the reader has been detached, or the input returned an error.
+	stopCtx      func() bool // This stops detaching the reader when its context is done, if it has one.
+	closed       bool
+	guard        useGuard
+}
+
+func (r *readerAtReader) Read(p []byte) (int, error) {
+	if err := r.guard.acquire(); err != nil {
+		return 0, err
+	}
+	defer r.guard.release()
+	if r.closed {
+		return 0, ErrClosed
+	}
+	return r.multeeReader.read(r, p)
+}
+
+// Returns the absolute offset in the input of the next byte this reader reads.
+// Unlike Read, this is concurrency-safe, and it can be called after Close.
+func (r *readerAtReader) Offset() int64 {
+	mr := r.multeeReader
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	return r.offset
+}
+
+// Returns the number of bytes that have been read by the fastest reader, but not by this reader yet.
+// Like Offset, this is concurrency-safe.
+func (r *readerAtReader) Lag() int64 {
+	mr := r.multeeReader
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	return mr.offset - r.offset
+}
+
+// Returns the current statistics of the reader.
+// Blocked is the time spent reading from the input.
+func (r *readerAtReader) Stats() ReaderStats {
+	mr := r.multeeReader
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	return r.stats
+}
+
+func (r *readerAtReader) Close() error {
+	if err := r.guard.acquire(); err != nil {
+		return err
+	}
+	defer r.guard.release()
+	if r.closed {
+		return ErrClosed
+	}
+	if r.stopCtx != nil {
+		r.stopCtx()
+	}
+	mr := r.multeeReader
+	mr.mu.Lock()
+	mr.detach(r, ErrClosed)
+	mr.mu.Unlock()
+	r.closed = true
+	return nil
+}
@@ -0,0 +1,88 @@
+// Copyright 2023-2025 Roel Harbers.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package multee
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewMulteeReaderAt(t *testing.T) {
+	t.Run("Readers_don't_wait_for_each_other", func(t *testing.T) {
+		mr := NewMulteeReaderAt(strings.NewReader("foobar"), 6)
+		r1 := mr.NewReader()
+		defer r1.Close()
+		r2 := mr.NewReader()
+		defer r2.Close()
+		// r1 is read to EOF, while r2 isn't read at all, in the same goroutine.
+		b, err := io.ReadAll(r1)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("foobar"), b)
+		assert.Equal(t, int64(6), mr.SourceOffset())
+		assert.Equal(t, int64(6), r2.Lag())
+		b, err = io.ReadAll(r2)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("foobar"), b)
+		assert.Equal(t, Stats{SourceBytes: 6, Chunks: 2, Readers: 2}, mr.Stats())
+	})
+	t.Run("NewReader_starts_at_source_offset", func(t *testing.T) {
+		mr := NewMulteeReaderAt(strings.NewReader("foobar"), -1)
+		r1 := mr.NewReader()
+		defer r1.Close()
+		_, err := io.ReadFull(r1, make([]byte, 3))
+		assert.NoError(t, err)
+		r2 := mr.NewReader()
+		defer r2.Close()
+		assert.Equal(t, int64(3), r2.Offset())
+		b, err := io.ReadAll(r2)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("bar"), b)
+	})
+	t.Run("NewReaderFrom", func(t *testing.T) {
+		mr := NewMulteeReaderAt(strings.NewReader("foobar"), 6)
+		r, err := mr.NewReaderFrom(2)
+		assert.NoError(t, err)
+		defer r.Close()
+		b, err := io.ReadAll(r)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("obar"), b)
+		_, err = mr.NewReaderFrom(7)
+		assert.Error(t, err)
+	})
+	t.Run("Size", func(t *testing.T) {
+		mr := NewMulteeReaderAt(strings.NewReader("foobar"), 3)
+		r := mr.NewReader()
+		defer r.Close()
+		b, err := io.ReadAll(r)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("foo"), b)
+	})
+	t.Run("Truncated", func(t *testing.T) {
+		mr := NewMulteeReaderAt(strings.NewReader("foobar"), 8)
+		r := mr.NewReader()
+		defer r.Close()
+		b, err := io.ReadAll(r)
+		assert.Equal(t, []byte("foobar"), b)
+		assert.True(t, errors.Is(err, ErrSourceTruncated))
+		var srcErr *SourceError
+		assert.True(t, errors.As(err, &srcErr))
+		assert.Equal(t, int64(6), srcErr.Offset)
+		// The error is final.
+		_, err2 := r.Read(make([]byte, 1))
+		assert.Equal(t, err, err2)
+	})
+	t.Run("Read_after_Close", func(t *testing.T) {
+		mr := NewMulteeReaderAt(strings.NewReader("foobar"), 6)
+		r := mr.NewReader()
+		assert.NoError(t, r.Close())
+		_, err := r.Read(make([]byte, 1))
+		assert.Equal(t, ErrClosed, err)
+		assert.Equal(t, 0, mr.Stats().Readers)
+	})
+}
@@ -0,0 +1,78 @@
+// Copyright 2023-2025 Roel Harbers.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package multee
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// ConsumerFunc consumes the input of a multeeReader, see Run.
+// It should return when ctx is done.
+type ConsumerFunc func(ctx context.Context, r io.Reader) error
+
+// Runner runs consumer funcs on a single input reader, see Run.
+type Runner struct {
+	// Options configure the multeeReader, see NewMulteeReader.
+	Options []Option
+	// CancelOnError cancels all consumer funcs when any of them returns an error.
+	CancelOnError bool
+}
+
+// Run reads src using a multeeReader, with a reader for each consumer func, and runs each func in its own goroutine.
+// The readers are always closed when their func returns, so a func that returns early doesn't block the other funcs.
+// Run waits for all funcs to return, and returns all their errors joined, if any.
+func Run(ctx context.Context, src io.Reader, fns ...ConsumerFunc) error {
+	return Runner{}.Run(ctx, src, fns...)
+}
+
+// Like the package-level Run, but configured by the Runner.
+// When CancelOnError is set, the context errors of the funcs that were cancelled are not returned.
+func (rn Runner) Run(ctx context.Context, src io.Reader, fns ...ConsumerFunc) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	mr := newMulteeReaderContext(ctx, src, rn.Options...)
+	// All readers must be created before any of them is read from, so they all start at the beginning of the input.
+	readers := make([]Reader, len(fns))
+	for idx := range fns {
+		readers[idx] = mr.NewReader()
+	}
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex // This guards errs and cancelled.
+		errs      []error
+		cancelled bool
+	)
+	wg.Add(len(fns))
+	for idx, fn := range fns {
+		go func(idx int, fn ConsumerFunc, r Reader) {
+			defer wg.Done()
+			defer r.Close()
+			err := fn(ctx, r)
+			if err == nil {
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if cancelled && errors.Is(err, context.Canceled) {
+				// This func only failed because another func failed.
+				return
+			}
+			errs = append(errs, fmt.Errorf("consumer %d: %w", idx, err))
+			if rn.CancelOnError && !cancelled {
+				cancelled = true
+				// The multeeReader is aborted before the reader of this func is closed, because the context only aborts it
+				// asynchronously, and another func could start a read from the input reader, that can't be interrupted, in between.
+				mr.fail(canceledError(context.Canceled))
+				cancel()
+			}
+		}(idx, fn, readers[idx])
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,76 @@
+// Copyright 2023-2025 Roel Harbers.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package multee_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ComaVN/multee"
+)
+
+func TestRun(t *testing.T) {
+	t.Run("All_consumers_succeed", func(t *testing.T) {
+		var mu sync.Mutex
+		var got []string
+		consumer := func(ctx context.Context, r io.Reader) error {
+			b, err := io.ReadAll(r)
+			mu.Lock()
+			defer mu.Unlock()
+			got = append(got, string(b))
+			return err
+		}
+		err := multee.Run(context.Background(), strings.NewReader("foo"), consumer, consumer, consumer)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"foo", "foo", "foo"}, got)
+	})
+	t.Run("Early_return_does_not_block", func(t *testing.T) {
+		errFoo := errors.New("foo")
+		err := multee.Run(context.Background(), strings.NewReader(strings.Repeat("foo", 100000)),
+			func(ctx context.Context, r io.Reader) error {
+				// Returns without reading anything.
+				return errFoo
+			},
+			func(ctx context.Context, r io.Reader) error {
+				b, err := io.ReadAll(r)
+				assert.Len(t, b, 300000)
+				return err
+			},
+		)
+		assert.ErrorIs(t, err, errFoo)
+		assert.ErrorContains(t, err, "consumer 0")
+	})
+	t.Run("Cancel_on_error", func(t *testing.T) {
+		errFoo := errors.New("foo")
+		inputR, inputW := io.Pipe()
+		defer inputW.Close()
+		go func() {
+			// Write the first chunk, then block.
+			_, _ = inputW.Write([]byte("foo"))
+		}()
+		err := multee.Runner{CancelOnError: true}.Run(context.Background(), inputR,
+			func(ctx context.Context, r io.Reader) error {
+				_, err := r.Read(make([]byte, 3))
+				if err != nil {
+					return err
+				}
+				return errFoo
+			},
+			func(ctx context.Context, r io.Reader) error {
+				// This would block forever, if it wasn't cancelled.
+				_, err := io.ReadAll(r)
+				return err
+			},
+		)
+		assert.ErrorIs(t, err, errFoo)
+		assert.NotErrorIs(t, err, context.Canceled)
+	})
+}
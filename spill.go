@@ -0,0 +1,154 @@
+// Copyright 2023-2025 Roel Harbers.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package multee
+
+import (
+	"fmt"
+	"os"
+)
+
+const defaultSegmentSize = 64 * 1024 * 1024
+
+// Keeps chunks that are still needed by slow readers, but that don't fit in the ring anymore, in temporary files.
+// All fields are guarded by the multeeReader's mu.
+type spill struct {
+	dir         string
+	segmentSize int64
+	firstSeq    uint64         // Sequence number of the first chunk in chunks.
+	chunks      []spilledChunk // Spilled chunks, ordered by sequence number without gaps.
+	current     *spillSegment  // The segment new chunks are written to, if any.
+}
+
+// A chunk that has been written to a spill segment.
+type spilledChunk struct {
+	segment     *spillSegment
+	pos         int64 // Position of the chunk in the segment file.
+	startOffset int64 // Stream offset of the first byte of the chunk.
+	endPos      int
+}
+
+// A temporary file, containing spilled chunks.
+type spillSegment struct {
+	file *os.File
+	size int64
+	live int // Number of chunks in this segment that are still needed, or are being written.
+}
+
+// Returns the spilled chunk with the given sequence number, or nil if it hasn't been spilled.
+func (s *spill) chunk(seq uint64) *spilledChunk {
+	if s == nil || seq < s.firstSeq || seq >= s.firstSeq+uint64(len(s.chunks)) {
+		return nil
+	}
+	return &s.chunks[seq-s.firstSeq]
+}
+
+// Returns the segment and position to write a chunk of the given length to, creating a new segment if needed.
+// The segment is kept until the chunk is released, or the write is cancelled.
+func (s *spill) reserve(length int) (*spillSegment, int64, error) {
+	if s.current == nil || s.current.size+int64(length) > s.segmentSize {
+		f, err := os.CreateTemp(s.dir, "multee-*.spill")
+		if err != nil {
+			return nil, 0, err
+		}
+		s.current = &spillSegment{file: f}
+	}
+	seg := s.current
+	pos := seg.size
+	seg.size += int64(length)
+	seg.live++
+	return seg, pos, nil
+}
+
+// Releases a segment reserved for a chunk, and removes its file if it's no longer needed.
+func (s *spill) release(seg *spillSegment) {
+	seg.live--
+	if seg.live > 0 {
+		return
+	}
+	if s.current == seg {
+		s.current = nil
+	}
+	seg.file.Close()
+	os.Remove(seg.file.Name())
+}
+
+// Releases all spilled chunks before the given sequence number.
+func (s *spill) releaseBefore(seq uint64) {
+	if s == nil {
+		return
+	}
+	for len(s.chunks) > 0 && s.firstSeq < seq {
+		s.release(s.chunks[0].segment)
+		s.chunks = s.chunks[1:]
+		s.firstSeq++
+	}
+	if len(s.chunks) == 0 {
+		s.firstSeq = seq
+	}
+}
+
+// Writes the chunk with the given sequence number from the ring to disk, so its slot can be reused.
+// Must be called with mu locked and loading set, mu is unlocked while writing.
+func (mr *multeeReader) spillChunk(seq uint64) error {
+	c := mr.chunk(seq)
+	seg, pos, err := mr.spill.reserve(c.endPos)
+	if err != nil {
+		return err
+	}
+	// Readers may still read this chunk from the ring while it's written, since both only read c.buf.
+	mr.unlocked(func() {
+		_, err = seg.file.WriteAt(c.buf[:c.endPos], pos)
+	})
+	if err != nil {
+		mr.spill.release(seg)
+		return err
+	}
+	if len(mr.spill.chunks) == 0 {
+		mr.spill.firstSeq = seq
+	}
+	mr.spill.chunks = append(mr.spill.chunks, spilledChunk{
+		segment:     seg,
+		pos:         pos,
+		startOffset: c.startOffset,
+		endPos:      c.endPos,
+	})
+	// Chunks that aren't needed anymore, may have been released while writing.
+	mr.releaseSpilled()
+	return nil
+}
+
+// Reads from the spilled chunk the given reader is reading, at the reader's position in it.
+// The caller advances the reader by the number of bytes it has used.
+// Must be called with mu locked, mu is unlocked while reading.
+func (mr *multeeReader) readSpilled(r *reader, sc *spilledChunk, p []byte) (int, error) {
+	file, pos := sc.segment.file, sc.pos+int64(r.bufOffset)
+	p = p[:min(len(p), sc.endPos-r.bufOffset)]
+	// The chunk can't be released while this reader still needs it, unless it's detached.
+	var n int
+	var err error
+	mr.unlocked(func() {
+		n, err = file.ReadAt(p, pos)
+	})
+	if r.err != nil {
+		return 0, r.err
+	}
+	if n < len(p) {
+		return n, fmt.Errorf("reading spilled chunk: %w", err)
+	}
+	return n, nil
+}
+
+// Releases all spilled chunks that are no longer needed by any reader.
+// Must be called with mu locked.
+func (mr *multeeReader) releaseSpilled() {
+	if mr.spill == nil || len(mr.spill.chunks) == 0 {
+		return
+	}
+	floor := mr.head + 1
+	for r := range mr.readers {
+		floor = min(floor, r.chunk)
+	}
+	mr.spill.releaseBefore(floor)
+}
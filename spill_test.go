@@ -0,0 +1,45 @@
+// Copyright 2023-2025 Roel Harbers.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package multee
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithSpillToDisk(t *testing.T) {
+	dir := t.TempDir()
+	mr := NewMulteeReader(iotest.OneByteReader(strings.NewReader("foobar")), WithSpillToDisk(dir, 2))
+	r1 := mr.NewReader()
+	defer r1.Close()
+	r2 := mr.NewReader()
+	defer r2.Close()
+	// r1 doesn't have to wait for r2, all chunks r2 still needs are spilled to disk.
+	b, err := io.ReadAll(r1)
+	assert.Equal(t, []byte("foobar"), b)
+	assert.Nil(t, err)
+	files, err := os.ReadDir(dir)
+	if assert.NoError(t, err) {
+		assert.Len(t, files, 3)
+	}
+	p := make([]byte, 2)
+	bytesRead, err := r2.Read(p)
+	assert.Equal(t, 1, bytesRead)
+	assert.Equal(t, []byte("f"), p[0:bytesRead])
+	assert.Nil(t, err)
+	b, err = io.ReadAll(r2)
+	assert.Equal(t, []byte("oobar"), b)
+	assert.Nil(t, err)
+	// All spilled chunks have been read by all readers.
+	files, err = os.ReadDir(dir)
+	if assert.NoError(t, err) {
+		assert.Len(t, files, 0)
+	}
+}
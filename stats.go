@@ -0,0 +1,41 @@
+// Copyright 2023-2025 Roel Harbers.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package multee
+
+import "time"
+
+// Stats are statistics of a MulteeReader.
+type Stats struct {
+	SourceBytes int64  // Number of bytes read from the input reader.
+	Chunks      uint64 // Number of chunks loaded from the input reader, including empty ones.
+	Readers     int    // Number of readers that haven't been closed or detached.
+}
+
+// ReaderStats are statistics of a single Reader.
+type ReaderStats struct {
+	Bytes   int64         // Number of bytes delivered to the reader.
+	Reads   int64         // Number of calls to Read, whether they delivered bytes or not. Calls to WriteTo and Chunks count as one read each.
+	Blocked time.Duration // Total time the reader has been waiting for other readers, or for the input reader.
+}
+
+// Returns the current statistics of the multeeReader.
+func (mr *multeeReader) Stats() Stats {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	return Stats{
+		SourceBytes: mr.offset,
+		Chunks:      mr.head,
+		Readers:     len(mr.readers),
+	}
+}
+
+// Returns the current statistics of the reader.
+// Unlike Read, this is concurrency-safe, and it can be called after Close.
+func (r *reader) Stats() ReaderStats {
+	mr := r.multeeReader
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	return r.stats
+}
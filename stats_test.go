@@ -0,0 +1,69 @@
+// Copyright 2023-2025 Roel Harbers.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package multee
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_multeeReader_Stats(t *testing.T) {
+	mr := NewMulteeReader(iotest.OneByteReader(strings.NewReader("foo")))
+	r1 := mr.NewReader()
+	r2 := mr.NewReader()
+	defer r2.Close()
+	assert.Equal(t, Stats{Readers: 2}, mr.Stats())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		// This waits for r2 to read each byte.
+		b, err := io.ReadAll(r1)
+		assert.Equal(t, []byte("foo"), b)
+		assert.Nil(t, err)
+		assert.NoError(t, r1.Close())
+	}()
+	// Let r1 wait for r2 a while.
+	assert.Eventually(t, func() bool { return r1.Stats().Bytes == 1 }, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	b, err := io.ReadAll(r2)
+	assert.Equal(t, []byte("foo"), b)
+	assert.Nil(t, err)
+	<-done
+	assert.Equal(t, Stats{SourceBytes: 3, Chunks: 4, Readers: 1}, mr.Stats())
+	stats := r1.Stats()
+	assert.Equal(t, int64(3), stats.Bytes)
+	// Three reads of a byte each, and the read returning io.EOF.
+	assert.Equal(t, int64(4), stats.Reads)
+	assert.Positive(t, stats.Blocked)
+}
+
+type testObserver struct {
+	events []string
+}
+
+func (o *testObserver) OnChunkLoad(n int, err error) { o.events = append(o.events, "load") }
+func (o *testObserver) OnReaderJoin()                { o.events = append(o.events, "join") }
+func (o *testObserver) OnReaderClose(err error)      { o.events = append(o.events, "close") }
+func (o *testObserver) OnError(err error)            { o.events = append(o.events, "error: "+err.Error()) }
+
+func TestWithObserver(t *testing.T) {
+	o := &testObserver{}
+	var hookErr error
+	mr := NewMulteeReader(iotest.ErrReader(errors.New("foo")), WithObserver(o), WithHooks(Hooks{
+		OnError: func(err error) { hookErr = err },
+	}))
+	r := mr.NewReader()
+	_, err := r.Read(make([]byte, 3))
+	assert.ErrorIs(t, err, ErrSource)
+	assert.NoError(t, r.Close())
+	assert.Equal(t, []string{"join", "load", "error: multeeReader input reader failed at offset 0: foo", "close"}, o.events)
+	assert.Equal(t, err, hookErr)
+}
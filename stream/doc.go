@@ -0,0 +1,13 @@
+// Copyright 2023-2025 Roel Harbers.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+// Package stream is the equivalent of the multee package for streams of values, instead of bytes.
+// A Broadcaster delivers each value of a source iterator to all its subscribers, in order,
+// making it possible to iterate over a single source several times, without restarting it.
+//
+// Like the readers of a multeeReader, each subscriber must be used in its own goroutine,
+// and must either be iterated until the end or closed, or the Broadcaster will block.
+//
+// This package requires Go 1.23 or later.
+package stream
@@ -0,0 +1,246 @@
+// Copyright 2023-2025 Roel Harbers.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+//go:build go1.23
+
+package stream
+
+import (
+	"errors"
+	"io"
+	"iter"
+	"sync"
+)
+
+var ErrClosed = errors.New("broadcaster already closed")
+
+// This is the error the subscribers get after a panic of the source.
+var errPanicked = errors.New("broadcaster source panicked")
+
+// Option configures a Broadcaster, see NewBroadcaster.
+type Option func(*config)
+
+type config struct {
+	lookahead int
+}
+
+// WithLookahead sets how many values the fastest subscribers may run ahead of the slowest subscriber.
+// The default lookahead is a single value, which means all subscribers get the same value in lockstep.
+func WithLookahead(lookahead int) Option {
+	return func(cfg *config) {
+		cfg.lookahead = lookahead
+	}
+}
+
+// Broadcaster delivers the values of a source iterator to all its subscribers.
+type Broadcaster[T any] struct {
+	next        func() (T, error, bool)
+	stop        func()
+	mu          sync.Mutex // This guards all fields below, and the pos and closed fields of all subscribers.
+	cond        *sync.Cond // This is broadcast whenever a value has been pulled from the source, or a slot in the ring may have been freed.
+	err         error      // This is set when the source is done, to io.EOF, or to the error it yielded, or to ErrClosed.
+	ring        []T        // Ring of values, the value with index idx is kept in ring[idx % len(ring)].
+	head        uint64     // Number of values pulled from the source.
+	loading     bool       // This is set while a subscriber is pulling the next value from the source, with mu unlocked.
+	slotWaiters int        // Number of subscribers waiting for a slot in the ring to be freed.
+	subscribers map[*Subscriber[T]]struct{}
+}
+
+// Returns a Broadcaster for source, configured by opts.
+// The source is only iterated while subscribers are getting values, and it's stopped when it yields an error.
+// Like a multeeReader, a Broadcaster keeps serving new subscribers after all subscribers have been closed,
+// so unless the source is iterated until the end, the Broadcaster must be closed, to stop the source.
+// Like the errors of the input reader of a multeeReader, that error is final,
+// and each subscriber gets it after all values before it.
+func NewBroadcaster[T any](source iter.Seq2[T, error], opts ...Option) *Broadcaster[T] {
+	cfg := config{lookahead: 1}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	next, stop := iter.Pull2(source)
+	b := &Broadcaster[T]{
+		next:        next,
+		stop:        stop,
+		ring:        make([]T, max(1, cfg.lookahead)),
+		subscribers: make(map[*Subscriber[T]]struct{}),
+	}
+	b.cond = sync.NewCond(&b.mu)
+	return b
+}
+
+// Returns a new Subscriber, that starts at the next value pulled from the source.
+// The caller must either keep iterating until the end or call Close(), or the Broadcaster will block.
+// The returned subscriber is *not* concurrency-safe.
+// It is safe to call Subscribe while other subscribers are iterating.
+func (b *Broadcaster[T]) Subscribe() *Subscriber[T] {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := &Subscriber[T]{
+		broadcaster: b,
+		pos:         b.head,
+	}
+	b.subscribers[s] = struct{}{}
+	return s
+}
+
+// Stops the source, after which each subscriber gets ErrClosed, after the values that were already pulled from the source.
+// Returns ErrClosed if the source was already stopped.
+func (b *Broadcaster[T]) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for b.loading {
+		// The source can't be stopped while it's being pulled from.
+		b.cond.Wait()
+	}
+	if b.err != nil {
+		return ErrClosed
+	}
+	b.finish(ErrClosed)
+	return nil
+}
+
+// Used internally by subscriber to get the next value, keeping track of position.
+func (b *Broadcaster[T]) get(s *Subscriber[T]) (T, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var zero T
+	for {
+		if s.closed {
+			return zero, ErrClosed
+		}
+		if s.pos < b.head {
+			v := b.ring[s.pos%uint64(len(b.ring))]
+			s.pos++
+			if b.slotWaiters > 0 {
+				// This subscriber may have been the last one holding up the ring.
+				b.cond.Broadcast()
+			}
+			return v, nil
+		}
+		// The calling subscriber has got all pulled values.
+		if b.err != nil {
+			return zero, b.err
+		}
+		if b.loading {
+			// Wait for the next value to be pulled.
+			b.cond.Wait()
+			continue
+		}
+		if !b.slotFree() {
+			// Wait for the slowest subscribers to free up a slot in the ring.
+			b.slotWaiters++
+			b.cond.Wait()
+			b.slotWaiters--
+			continue
+		}
+		// Let the first subscriber to get here pull the next value.
+		b.load()
+	}
+}
+
+// Returns whether the slot for the next value is no longer needed by any subscriber.
+// Must be called with mu locked.
+func (b *Broadcaster[T]) slotFree() bool {
+	for s := range b.subscribers {
+		if s.pos+uint64(len(b.ring)) <= b.head {
+			return false
+		}
+	}
+	return true
+}
+
+// Pulls the next value from the source into the ring.
+// Must be called with mu locked, and only when no subscriber needs the value in its slot anymore.
+// Since loading is set, no-one else is using the source or the slot while mu is unlocked.
+// If the source panics, it can't be resumed, so the subscribers get errPanicked after the values before it.
+func (b *Broadcaster[T]) load() {
+	b.loading = true
+	defer func() {
+		if b.loading {
+			// Pulling was interrupted by a panic.
+			b.loading = false
+			b.finish(errPanicked)
+		}
+	}()
+	v, err, ok := b.pull()
+	b.loading = false
+	switch {
+	case !ok:
+		b.finish(io.EOF)
+	case err != nil:
+		b.finish(err)
+	default:
+		b.ring[b.head%uint64(len(b.ring))] = v
+		b.head++
+	}
+	b.cond.Broadcast()
+}
+
+// Pulls the next value from the source with mu unlocked, and locks mu again afterwards, even when the source panics,
+// so the deferred unlocks of the callers don't unlock an unlocked mutex.
+// Must be called with mu locked.
+func (b *Broadcaster[T]) pull() (T, error, bool) {
+	b.mu.Unlock()
+	defer b.mu.Lock()
+	return b.next()
+}
+
+// Stops the source, and makes err the error each subscriber gets after all pulled values.
+// Must be called with mu locked, while not loading.
+func (b *Broadcaster[T]) finish(err error) {
+	b.err = err
+	b.stop()
+	b.cond.Broadcast()
+}
+
+// Subscriber gets the values of a Broadcaster, see Broadcaster.Subscribe.
+type Subscriber[T any] struct {
+	broadcaster *Broadcaster[T]
+	pos         uint64 // Index of the next value this subscriber gets.
+	closed      bool
+}
+
+// Returns the next value, or io.EOF after the last value.
+// When the source yielded an error, or the Broadcaster was closed, that error is returned after the values before it.
+// Like the errors of Read, the error is final.
+func (s *Subscriber[T]) Next() (T, error) {
+	return s.broadcaster.get(s)
+}
+
+// Returns an iterator over the remaining values.
+// When the source yielded an error, or the Broadcaster was closed, the iterator yields that error with the zero value of T, and ends.
+// The subscriber is closed when the iteration ends, even when it ends early.
+func (s *Subscriber[T]) All() iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		defer s.Close()
+		for {
+			v, err := s.Next()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				yield(v, err)
+				return
+			}
+			if !yield(v, nil) {
+				return
+			}
+		}
+	}
+}
+
+// Removes the subscriber from the Broadcaster, so the other subscribers no longer wait for it.
+// Returns ErrClosed if the subscriber was already closed.
+func (s *Subscriber[T]) Close() error {
+	b := s.broadcaster
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if s.closed {
+		return ErrClosed
+	}
+	s.closed = true
+	delete(b.subscribers, s)
+	b.cond.Broadcast()
+	return nil
+}
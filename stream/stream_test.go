@@ -0,0 +1,183 @@
+// Copyright 2023-2025 Roel Harbers.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+//go:build go1.23
+
+package stream
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Returns a source yielding the integers 0 to n-1, followed by err, if it's not nil.
+func ints(n int, err error) iter.Seq2[int, error] {
+	return func(yield func(int, error) bool) {
+		for i := 0; i < n; i++ {
+			if !yield(i, nil) {
+				return
+			}
+		}
+		if err != nil {
+			yield(0, err)
+		}
+	}
+}
+
+// Collects all values of each subscriber, each in its own goroutine.
+func collectAll(subscribers []*Subscriber[int]) ([][]int, []error) {
+	got := make([][]int, len(subscribers))
+	errs := make([]error, len(subscribers))
+	var wg sync.WaitGroup
+	wg.Add(len(subscribers))
+	for idx, s := range subscribers {
+		go func(idx int, s *Subscriber[int]) {
+			defer wg.Done()
+			got[idx] = []int{}
+			for v, err := range s.All() {
+				if err != nil {
+					errs[idx] = err
+					break
+				}
+				got[idx] = append(got[idx], v)
+			}
+		}(idx, s)
+	}
+	wg.Wait()
+	return got, errs
+}
+
+func TestBroadcaster(t *testing.T) {
+	for _, lookahead := range []int{0, 1, 3, 1000} {
+		t.Run(fmt.Sprintf("Lookahead_%d", lookahead), func(t *testing.T) {
+			b := NewBroadcaster(ints(100, nil), WithLookahead(lookahead))
+			subscribers := []*Subscriber[int]{b.Subscribe(), b.Subscribe(), b.Subscribe()}
+			got, errs := collectAll(subscribers)
+			want := make([]int, 100)
+			for i := range want {
+				want[i] = i
+			}
+			for idx := range subscribers {
+				assert.Equal(t, want, got[idx], "subscriber %d", idx)
+				assert.NoError(t, errs[idx], "subscriber %d", idx)
+			}
+		})
+	}
+	t.Run("Source_error", func(t *testing.T) {
+		srcErr := errors.New("foo")
+		b := NewBroadcaster(ints(3, srcErr))
+		got, errs := collectAll([]*Subscriber[int]{b.Subscribe(), b.Subscribe()})
+		for idx := range got {
+			assert.Equal(t, []int{0, 1, 2}, got[idx])
+			assert.Equal(t, srcErr, errs[idx])
+		}
+	})
+	t.Run("Source_panic", func(t *testing.T) {
+		b := NewBroadcaster(func(yield func(int, error) bool) {
+			if yield(0, nil) {
+				panic("foo")
+			}
+		})
+		s1 := b.Subscribe()
+		defer s1.Close()
+		s2 := b.Subscribe()
+		defer s2.Close()
+		for _, s := range []*Subscriber[int]{s1, s2} {
+			v, err := s.Next()
+			assert.Equal(t, 0, v)
+			assert.NoError(t, err)
+		}
+		assert.PanicsWithValue(t, "foo", func() {
+			s1.Next()
+		})
+		// The Broadcaster is still usable, but the source can't be resumed.
+		for _, s := range []*Subscriber[int]{s1, s2} {
+			_, err := s.Next()
+			assert.Equal(t, errPanicked, err)
+		}
+		assert.Equal(t, ErrClosed, b.Close())
+	})
+	t.Run("Next", func(t *testing.T) {
+		b := NewBroadcaster(ints(1, nil))
+		s := b.Subscribe()
+		defer s.Close()
+		v, err := s.Next()
+		assert.Equal(t, 0, v)
+		assert.NoError(t, err)
+		_, err = s.Next()
+		assert.Equal(t, io.EOF, err)
+		_, err = s.Next()
+		assert.Equal(t, io.EOF, err)
+	})
+	t.Run("Break_closes", func(t *testing.T) {
+		b := NewBroadcaster(ints(100, nil))
+		s1 := b.Subscribe()
+		s2 := b.Subscribe()
+		for range s1.All() {
+			break
+		}
+		assert.Equal(t, ErrClosed, s1.Close())
+		// s2 isn't held up by s1.
+		got, errs := collectAll([]*Subscriber[int]{s2})
+		assert.Len(t, got[0], 100)
+		assert.NoError(t, errs[0])
+	})
+	t.Run("Subscribe_after_all_closed", func(t *testing.T) {
+		stopped := false
+		b := NewBroadcaster(func(yield func(int, error) bool) {
+			defer func() {
+				stopped = true
+			}()
+			ints(100, nil)(yield)
+		})
+		s1 := b.Subscribe()
+		for range s1.All() {
+			break
+		}
+		// Like the readers of a multeeReader, a new subscriber starts at the next value.
+		s2 := b.Subscribe()
+		v, err := s2.Next()
+		assert.Equal(t, 1, v)
+		assert.NoError(t, err)
+		assert.NoError(t, s2.Close())
+		assert.False(t, stopped)
+		// Closing the Broadcaster stops the source, so its goroutine doesn't leak.
+		assert.NoError(t, b.Close())
+		assert.True(t, stopped)
+	})
+	t.Run("Subscribe_while_iterating", func(t *testing.T) {
+		b := NewBroadcaster(ints(5, nil))
+		s1 := b.Subscribe()
+		defer s1.Close()
+		_, err := s1.Next()
+		assert.NoError(t, err)
+		s2 := b.Subscribe()
+		defer s2.Close()
+		// s2 starts at the next value pulled from the source.
+		go func() {
+			for range s1.All() {
+			}
+		}()
+		v, err := s2.Next()
+		assert.Equal(t, 1, v)
+		assert.NoError(t, err)
+	})
+	t.Run("Close", func(t *testing.T) {
+		b := NewBroadcaster(ints(100, nil))
+		s := b.Subscribe()
+		defer s.Close()
+		_, err := s.Next()
+		assert.NoError(t, err)
+		assert.NoError(t, b.Close())
+		assert.Equal(t, ErrClosed, b.Close())
+		_, err = s.Next()
+		assert.Equal(t, ErrClosed, err)
+	})
+}
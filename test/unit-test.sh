@@ -9,3 +9,7 @@ printf "Running unit tests:\n"
 go test -coverprofile=coverage.out
 go tool cover -html=coverage.out -o coverage.html
 printf "See coverage.html for more details\n\n"
+
+printf "Running unit tests and conformance tests of all implementations with the race detector:\n"
+go test -race ./...
+printf "\n"
@@ -0,0 +1,100 @@
+// Copyright 2023-2025 Roel Harbers.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package multee
+
+import "io"
+
+// MulteeWriter is a MulteeReader that's fed by writing to it, instead of by reading from an input reader.
+type MulteeWriter interface {
+	MulteeReader
+	io.WriteCloser
+	// Like Close, but the readers get an error matching err, instead of io.EOF, after reading all bytes written before.
+	CloseWithError(err error) error
+}
+
+var _ MulteeWriter = (*multeeWriter)(nil)
+
+type multeeWriter struct {
+	*multeeReader
+}
+
+// Returns a MulteeWriter, configured by opts, see NewMulteeReader.
+// Each write is buffered in one or more chunks of its own, so many small writes are best buffered first, eg. by a bufio.Writer.
+// Note that bytes written while there are no readers are lost.
+func NewMulteeWriter(opts ...Option) MulteeWriter {
+	return &multeeWriter{newMulteeReader(nil, opts...)}
+}
+
+// Write blocks until all of p is buffered, which is when the readers have made room for it,
+// following the same rules as a multeeReader reading from its input reader.
+func (mw *multeeWriter) Write(p []byte) (int, error) {
+	mr := mw.multeeReader
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	written := 0
+	for len(p) > 0 {
+		if mr.abortErr != nil {
+			return written, mr.abortErr
+		}
+		if mr.err != nil {
+			return written, ErrClosed
+		}
+		if mr.loading {
+			// Wait for another write.
+			mr.cond.Wait()
+			continue
+		}
+		if mr.spill == nil && !mr.slotFree() {
+			if mr.checkSlowReaders() {
+				// Some readers were too slow, the state may have changed.
+				continue
+			}
+			// Wait for the slowest readers to free up a slot in the ring.
+			mr.slotWaiters++
+			mr.cond.Wait()
+			mr.slotWaiters--
+			continue
+		}
+		c := mr.startLoad()
+		if c == nil {
+			continue
+		}
+		n := copy(c.buf, p)
+		mr.finishLoad(c, n, nil)
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+// Close makes the readers return io.EOF, after reading all bytes written before.
+func (mw *multeeWriter) Close() error {
+	return mw.CloseWithError(nil)
+}
+
+func (mw *multeeWriter) CloseWithError(err error) error {
+	if err == nil {
+		err = io.EOF
+	}
+	mr := mw.multeeReader
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	for mr.loading {
+		// Wait for a write.
+		mr.cond.Wait()
+	}
+	if mr.err != nil {
+		return ErrClosed
+	}
+	// This doesn't need a chunk of its own, since the readers only return the error after reading the last chunk.
+	// For the same reason, no chunk load is reported, only the error, if it isn't io.EOF.
+	mr.err = sourceError(err, mr.offset)
+	if mr.err != io.EOF {
+		mr.cfg.observers.error(mr.err)
+	}
+	mr.stopStallTimer()
+	mr.cond.Broadcast()
+	return nil
+}
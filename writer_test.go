@@ -0,0 +1,91 @@
+// Copyright 2023-2025 Roel Harbers.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package multee
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewMulteeWriter(t *testing.T) {
+	t.Run("Three_readers", func(t *testing.T) {
+		input := strings.Repeat("foobar", 10000)
+		mw := NewMulteeWriter(WithChunkSize(1000))
+		var wg sync.WaitGroup
+		for i := 0; i < 3; i++ {
+			r := mw.NewReader()
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer r.Close()
+				b, err := io.ReadAll(r)
+				assert.Equal(t, input, string(b))
+				assert.Nil(t, err)
+			}()
+		}
+		for i := 0; i < len(input); i += 6 {
+			bytesWritten, err := mw.Write([]byte(input[i : i+6]))
+			assert.Equal(t, 6, bytesWritten)
+			assert.Nil(t, err)
+		}
+		assert.NoError(t, mw.Close())
+		wg.Wait()
+		assert.ErrorIs(t, mw.Close(), ErrClosed)
+		_, err := mw.Write([]byte("foo"))
+		assert.ErrorIs(t, err, ErrClosed)
+	})
+	t.Run("Write_blocks_until_read", func(t *testing.T) {
+		mw := NewMulteeWriter(WithChunkSize(3))
+		r := mw.NewReader()
+		defer r.Close()
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			// The first chunk fits in the ring, the second has to wait for r.
+			bytesWritten, err := mw.Write([]byte("foobar"))
+			assert.Equal(t, 6, bytesWritten)
+			assert.Nil(t, err)
+			assert.NoError(t, mw.Close())
+		}()
+		b, err := io.ReadAll(r)
+		assert.Equal(t, []byte("foobar"), b)
+		assert.Nil(t, err)
+		<-done
+	})
+	t.Run("Close_with_error", func(t *testing.T) {
+		errFoo := errors.New("foo")
+		mw := NewMulteeWriter()
+		r := mw.NewReader()
+		defer r.Close()
+		go func() {
+			_, _ = mw.Write([]byte("foo"))
+			_ = mw.CloseWithError(errFoo)
+		}()
+		b, err := io.ReadAll(r)
+		assert.Equal(t, []byte("foo"), b)
+		assert.ErrorIs(t, err, errFoo)
+		assert.ErrorIs(t, err, ErrSource)
+	})
+	t.Run("Observer", func(t *testing.T) {
+		o := &testObserver{}
+		mw := NewMulteeWriter(WithObserver(o))
+		r := mw.NewReader()
+		defer r.Close()
+		go func() {
+			_, _ = mw.Write([]byte("foo"))
+			_ = mw.CloseWithError(errors.New("foo"))
+		}()
+		_, err := io.ReadAll(r)
+		assert.Error(t, err)
+		// Closing doesn't load a chunk, so it's only reported as an error.
+		assert.Equal(t, []string{"join", "load", "error: multeeReader input reader failed at offset 3: foo"}, o.events)
+		assert.Equal(t, uint64(1), mw.Stats().Chunks)
+	})
+}